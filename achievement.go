@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// achievementCounter is a game counter watched by achievements. Most
+// counters are stats fields. Level counters count consecutive levels, up to
+// the current one, that satisfy some condition.
+type achievementCounter int
+
+// Achievement counters.
+const (
+	NoCounter achievementCounter = iota // achievement obtained on a game event
+	CounterBananas
+	CounterHarmonicMagUse
+	CounterOricMagUse
+	CounterFireUse
+	CounterDestructionUse
+	CounterOricTelUse
+	CounterJumps
+	CounterClimbedTree
+	CounterTableHides
+	CounterHoledWallsCrawled
+	CounterDoorsOpened
+	CounterBarrelHides
+	CounterExtinguishments
+	CounterLore
+	CounterLorePerc
+	CounterRest
+	CounterExploredLevels
+	CounterStealthyLevels
+	CounterVeryStealthyLevels
+	CounterRestlessLevels
+	CounterMagaralessLevels
+)
+
+// Value returns the current value of the counter.
+func (c achievementCounter) Value(g *game) int {
+	st := &g.Stats
+	switch c {
+	case CounterBananas:
+		return g.Player.Bananas
+	case CounterHarmonicMagUse:
+		return st.HarmonicMagUse
+	case CounterOricMagUse:
+		return st.OricMagUse
+	case CounterFireUse:
+		return st.FireUse
+	case CounterDestructionUse:
+		return st.DestructionUse
+	case CounterOricTelUse:
+		return st.OricTelUse
+	case CounterJumps:
+		return st.Jumps + st.WallJumps
+	case CounterClimbedTree:
+		return st.ClimbedTree
+	case CounterTableHides:
+		return st.TableHides
+	case CounterHoledWallsCrawled:
+		return st.HoledWallsCrawled
+	case CounterDoorsOpened:
+		return st.DoorsOpened
+	case CounterBarrelHides:
+		return st.BarrelHides
+	case CounterExtinguishments:
+		return st.Extinguishments
+	case CounterLore:
+		return len(st.Lore)
+	case CounterLorePerc:
+		if len(g.Params.Lore) == 0 {
+			return 0
+		}
+		return len(st.Lore) * 100 / len(g.Params.Lore)
+	case CounterRest:
+		return st.Rest
+	case CounterExploredLevels:
+		return g.levelStreak(func(depth int) bool { return st.DExplPerc[depth] > 93 })
+	case CounterStealthyLevels:
+		return g.stealthStreak(1)
+	case CounterVeryStealthyLevels:
+		return g.stealthStreak(2)
+	case CounterRestlessLevels:
+		return g.levelStreak(func(depth int) bool { return st.DRests[depth] == 0 })
+	case CounterMagaralessLevels:
+		return g.levelStreak(func(depth int) bool { return st.DMagaraUses[depth] == 0 })
+	}
+	return 0
+}
+
+// levelStreak returns the number of consecutive levels that satisfy a
+// condition, up to the current one.
+func (g *game) levelStreak(ok func(depth int) bool) int {
+	n := 0
+	for depth := min(g.Depth, MaxDepth); depth >= 1 && ok(depth); depth-- {
+		n++
+	}
+	return n
+}
+
+// stealthStreak returns the number of consecutive levels, up to the current
+// one, where the player was spotted by less than 3 monsters, counting only
+// unaware monsters on the last n levels.
+func (g *game) stealthStreak(n int) int {
+	st := &g.Stats
+	return g.levelStreak(func(depth int) bool {
+		if depth > g.Depth-n {
+			return st.DUSpotted[depth] < 3
+		}
+		return st.DSpotted[depth] < 3
+	})
+}
+
+// achievementTier is a tier of an achievement family, obtained when the
+// family's counter reaches the threshold, if the player is at least at a
+// given depth. A tier may watch its own counter instead.
+type achievementTier struct {
+	Ach       achievement
+	Threshold int
+	MinDepth  int
+	Counter   achievementCounter // replaces the family's counter, if any
+}
+
+// achievementDef defines a family of achievements sharing a description and
+// a counter, such as the novice, initiate and master tiers of an
+// achievement, with increasing thresholds. Achievements obtained on a game
+// event have no counter and a single tier.
+type achievementDef struct {
+	Desc    string
+	Counter achievementCounter
+	Tiers   []achievementTier
+}
+
+// achievements lists the achievements that can be earned, in display order.
+// NoAchievement is not listed, as it is only a consolation.
+var achievements = []achievementDef{
+	{Desc: "carry as many bananas as possible", Counter: CounterBananas, Tiers: []achievementTier{
+		{Ach: AchBananaCollector, Threshold: MaxBananas}}},
+	{Desc: "evoke harmonic magaras", Counter: CounterHarmonicMagUse, Tiers: []achievementTier{
+		{Ach: AchHarmonistNovice, Threshold: 6},
+		{Ach: AchHarmonistInitiate, Threshold: 11},
+		{Ach: AchHarmonistMaster, Threshold: 16}}},
+	{Desc: "evoke oric magaras", Counter: CounterOricMagUse, Tiers: []achievementTier{
+		{Ach: AchNoviceOricCelmist, Threshold: 6},
+		{Ach: AchInitiateOricCelmist, Threshold: 11},
+		{Ach: AchMasterOricCelmist, Threshold: 16}}},
+	{Desc: "get spotted by most monsters of a level", Tiers: []achievementTier{
+		{Ach: AchUnstealthy}}},
+	{Desc: "leave levels spotted by less than 3 monsters, only unaware ones on the last levels", Counter: CounterStealthyLevels, Tiers: []achievementTier{
+		{Ach: AchStealthNovice, Threshold: 1},
+		{Ach: AchStealthInitiate, Threshold: 3, MinDepth: 5},
+		{Ach: AchStealthMaster, Threshold: 4, MinDepth: 8, Counter: CounterVeryStealthyLevels}}},
+	{Desc: "evoke fire magaras", Counter: CounterFireUse, Tiers: []achievementTier{
+		{Ach: AchPyromancerNovice, Threshold: 2},
+		{Ach: AchPyromancerInitiate, Threshold: 4},
+		{Ach: AchPyromancerMaster, Threshold: 6}}},
+	{Desc: "destroy walls", Counter: CounterDestructionUse, Tiers: []achievementTier{
+		{Ach: AchDestructorNovice, Threshold: 20},
+		{Ach: AchDestructorInitiate, Threshold: 40},
+		{Ach: AchDestructorMaster, Threshold: 60}}},
+	{Desc: "evoke oric teleportation magaras", Counter: CounterOricTelUse, Tiers: []achievementTier{
+		{Ach: AchTeleport, Threshold: 14}}},
+	{Desc: "equip a cloak", Tiers: []achievementTier{{Ach: AchCloak}}},
+	{Desc: "equip an amulet", Tiers: []achievementTier{{Ach: AchAmulet}}},
+	{Desc: "rescue Shaedra", Tiers: []achievementTier{{Ach: AchRescuedShaedra}}},
+	{Desc: "recover the Gem Portal Artifact", Tiers: []achievementTier{{Ach: AchRetrievedArtifact}}},
+	{Desc: "jump over monsters or propel yourself against walls", Counter: CounterJumps, Tiers: []achievementTier{
+		{Ach: AchAcrobat, Threshold: 15}}},
+	{Desc: "climb different trees", Counter: CounterClimbedTree, Tiers: []achievementTier{
+		{Ach: AchTree, Threshold: 12}}},
+	{Desc: "hide under different tables", Counter: CounterTableHides, Tiers: []achievementTier{
+		{Ach: AchTable, Threshold: 12}}},
+	{Desc: "crawl through different holed walls", Counter: CounterHoledWallsCrawled, Tiers: []achievementTier{
+		{Ach: AchHole, Threshold: 12}}},
+	{Desc: "open different doors", Counter: CounterDoorsOpened, Tiers: []achievementTier{
+		{Ach: AchDoors, Threshold: 100}}},
+	{Desc: "hide in different barrels", Counter: CounterBarrelHides, Tiers: []achievementTier{
+		{Ach: AchBarrels, Threshold: 20}}},
+	{Desc: "extinguish fires", Counter: CounterExtinguishments, Tiers: []achievementTier{
+		{Ach: AchExtinguisher, Threshold: 15}}},
+	{Desc: "read lore messages", Counter: CounterLore, Tiers: []achievementTier{
+		{Ach: AchLoreStudent, Threshold: 4}}},
+	{Desc: "read all lore messages (percent)", Counter: CounterLorePerc, Tiers: []achievementTier{
+		{Ach: AchLoremaster, Threshold: 100}}},
+	{Desc: "explore most of levels", Counter: CounterExploredLevels, Tiers: []achievementTier{
+		{Ach: AchNoviceExplorer, Threshold: 1},
+		{Ach: AchInitiateExplorer, Threshold: 3, MinDepth: 5},
+		{Ach: AchMasterExplorer, Threshold: 5, MinDepth: 8}}},
+	{Desc: "kill a monster in sight", Tiers: []achievementTier{{Ach: AchAssassin}}},
+	{Desc: "leave levels without resting", Counter: CounterRestlessLevels, Tiers: []achievementTier{
+		{Ach: AchInsomniaNovice, Threshold: 2, MinDepth: 3},
+		{Ach: AchInsomniaInitiate, Threshold: 4, MinDepth: 5},
+		{Ach: AchInsomniaMaster, Threshold: 6, MinDepth: 8}}},
+	{Desc: "rest in barrels", Counter: CounterRest, Tiers: []achievementTier{
+		{Ach: AchSleepy, Threshold: 10}}},
+	{Desc: "leave levels without evoking magaras", Counter: CounterMagaralessLevels, Tiers: []achievementTier{
+		{Ach: AchAntimagicNovice, Threshold: 2, MinDepth: 3},
+		{Ach: AchAntimagicInitiate, Threshold: 4, MinDepth: 5},
+		{Ach: AchAntimagicMaster, Threshold: 6, MinDepth: 8}}},
+	{Desc: "escape with the pacifist conduct", Tiers: []achievementTier{{Ach: AchPacifist}}},
+	{Desc: "escape with the magaraless conduct", Tiers: []achievementTier{{Ach: AchMagaraless}}},
+	{Desc: "escape with the restless conduct", Tiers: []achievementTier{{Ach: AchRestless}}},
+	{Desc: "escape with the unseen conduct", Tiers: []achievementTier{{Ach: AchUnseen}}},
+}
+
+// achievementGoal is an achievement tier along with its family's
+// description and counter.
+type achievementGoal struct {
+	Ach       achievement
+	Desc      string
+	Counter   achievementCounter
+	Threshold int
+	MinDepth  int
+}
+
+// expandAchievements returns the tiers of achievement families, in order.
+func expandAchievements(defs []achievementDef) []achievementGoal {
+	goals := []achievementGoal{}
+	for _, def := range defs {
+		for _, tier := range def.Tiers {
+			goal := achievementGoal{Ach: tier.Ach, Desc: def.Desc, Counter: def.Counter,
+				Threshold: tier.Threshold, MinDepth: tier.MinDepth}
+			if tier.Counter != NoCounter {
+				goal.Counter = tier.Counter
+			}
+			goals = append(goals, goal)
+		}
+	}
+	return goals
+}
+
+// achievementGoals contains every achievement tier, in display order.
+var achievementGoals = expandAchievements(achievements)
+
+// CheckAchievements awards the achievements watching the given counters
+// whose threshold has been reached.
+func (g *game) CheckAchievements(counters ...achievementCounter) {
+	for _, def := range achievementGoals {
+		if def.Counter == NoCounter || g.Depth < def.MinDepth {
+			continue
+		}
+		for _, c := range counters {
+			if def.Counter == c && c.Value(g) >= def.Threshold {
+				def.Ach.Get(g)
+			}
+		}
+	}
+}
+
+// achievementLine returns the line of an achievement in the achievements
+// screen: its name, progress and description.
+func (g *game) achievementLine(def achievementGoal) ui.StyledText {
+	_, earned := g.Stats.Achievements[def.Ach]
+	progress := "-"
+	switch {
+	case earned:
+		progress = "done"
+	case def.Counter != NoCounter:
+		progress = fmt.Sprintf("%d/%d", min(def.Counter.Value(g), def.Threshold), def.Threshold)
+	}
+	desc := def.Desc
+	if def.MinDepth > 0 {
+		desc = fmt.Sprintf("%s (from depth %d)", desc, def.MinDepth)
+	}
+	st := gruid.Style{}
+	if earned {
+		st = st.WithFg(ColorYellow)
+	}
+	return ui.Textf(" %-30s %7s  %s", def.Ach+":", progress, desc).WithStyle(st)
+}
+
+func (md *model) openAchievements() {
+	g := md.g
+	md.pagerMode = modeAchievements
+	md.mode = modePager
+	md.pager.SetBox(&ui.Box{Title: ui.Text(" Achievements ").WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	lines := []ui.StyledText{}
+	for _, def := range achievementGoals {
+		lines = append(lines, g.achievementLine(def))
+	}
+	md.pager.SetLines(lines)
+	md.pager.SetCursor(gruid.Point{0, 0})
+}
@@ -66,6 +66,13 @@ const (
 
 	ActionZoomIncrease
 	ActionZoomDecrease
+
+	ActionDailyLedger
+	ActionMorgue
+	ActionCareer
+	ActionHighScores
+	ActionConducts
+	ActionAchievements
 )
 
 var ConfigurableKeyActions = [...]action{
@@ -202,6 +209,18 @@ func (k action) String() (text string) {
 		text = "increase zoom"
 	case ActionZoomDecrease:
 		text = "decrease zoom"
+	case ActionDailyLedger:
+		text = "Daily challenge results"
+	case ActionMorgue:
+		text = "Game history"
+	case ActionCareer:
+		text = "Career statistics"
+	case ActionHighScores:
+		text = "High scores"
+	case ActionConducts:
+		text = "Conducts"
+	case ActionAchievements:
+		text = "Achievements"
 	}
 	return text
 }
@@ -364,79 +383,25 @@ func (md *model) normalModeAction(action action) (again bool, eff gruid.Effect,
 		g.WaitTurn()
 	case ActionGoToStairs:
 		again = true
-		stairs := g.StairsSlice()
-		sortedStairs := g.SortedNearestTo(stairs, g.Player.P)
-		if len(sortedStairs) > 0 {
-			stair := sortedStairs[0]
-			if g.Player.P == stair {
-				err = errors.New("You are already on the stairs.")
-				break
-			}
+		var stair gruid.Point
+		stair, err = g.GoToStairs()
+		if valid(stair) {
 			md.targ.ex.p = stair
-			err = md.target()
-			if err != nil {
-				err = errors.New("There is no safe path to the nearest stairs.")
-			} else if !g.MoveToTarget() {
-				err = errors.New("You could not move toward stairs.")
-			}
-		} else {
-			err = errors.New("You cannot go to any stairs.")
 		}
 	case ActionInteract:
 		c := g.Dungeon.Cell(g.Player.P)
 		switch terrain(c) {
-		case StairCell:
-			if terrain(g.Dungeon.Cell(g.Player.P)) == StairCell && g.Objects.Stairs[g.Player.P] != BlockedStair {
-				// TODO: animation
-				//ui.MenuSelectedAnimation(MenuInteract, true)
-				strt := g.Objects.Stairs[g.Player.P]
-				err = md.checkShaedra(strt)
-				if err != nil {
-					break
-				}
-				again = true
-				if g.Descend(DescendNormal) {
-					md.win()
-				}
-				//ui.DrawDungeonView(NormalMode)
-			} else if terrain(g.Dungeon.Cell(g.Player.P)) == StairCell && g.Objects.Stairs[g.Player.P] == BlockedStair {
-				err = errors.New("The stairs are blocked by a magical stone barrier energies.")
-			} else {
-				err = errors.New("No stairs here.")
-			}
-		case BarrelCell:
-			//ui.MenuSelectedAnimation(MenuInteract, true)
-			err = g.Rest()
-			//if err != nil {
-			//ui.MenuSelectedAnimation(MenuInteract, false)
-			//}
 		case MagaraCell:
 			again = true
 			md.equipMagaraMenu()
-		case StoneCell:
-			//ui.MenuSelectedAnimation(MenuInteract, true)
-			err = g.ActivateStone()
-			//if err != nil {
-			//ui.MenuSelectedAnimation(MenuInteract, false)
-			//}
 		case ScrollCell:
 			again = true
 			md.readScroll()
-		case ItemCell:
-			err = md.g.EquipItem()
-		case LightCell:
-			err = g.ExtinguishFire()
-		case StoryCell:
-			if g.Objects.Story[g.Player.P] == StoryArtifact && !g.LiberatedArtifact {
-				g.PushEventFirst(&playerEvent{Action: StorySequence}, g.Turn)
-				g.LiberatedArtifact = true
-			} else if g.Objects.Story[g.Player.P] == StoryArtifactSealed {
-				err = errors.New("The artifact is protected by a magical stone barrier.")
-			} else {
-				err = errors.New("You cannot interact with anything here.")
-			}
 		default:
-			err = errors.New("You cannot interact with anything here.")
+			again, err = g.Interact()
+			if g.Depth == -1 {
+				md.win()
+			}
 		}
 	case ActionEvoke:
 		again = true
@@ -511,7 +476,7 @@ func (md *model) normalModeAction(action action) (again bool, eff gruid.Effect,
 			g.RescuedShaedra()
 		}
 		if g.Wizard && g.Depth < MaxDepth {
-			g.StoryPrint("Descended wizardly")
+			g.StoryEntry(timelineEntry{Kind: TimelineDescent, Name: "wizard", Text: "Descended wizardly"})
 			again = true
 			if g.Descend(DescendNormal) {
 				md.win()
@@ -562,6 +527,24 @@ func (md *model) normalModeAction(action action) (again bool, eff gruid.Effect,
 		}
 		md.updateStatusInfo()
 		md.mode = modeNormal
+	case ActionDailyLedger:
+		again = true
+		md.openDailyLedger()
+	case ActionMorgue:
+		again = true
+		md.openMorgue()
+	case ActionCareer:
+		again = true
+		md.openCareer()
+	case ActionHighScores:
+		again = true
+		md.openHighScores()
+	case ActionConducts:
+		again = true
+		md.openConducts()
+	case ActionAchievements:
+		again = true
+		md.openAchievements()
 	case ActionWizardInfo:
 		again = true
 		md.wizardInfo()
@@ -628,16 +611,7 @@ func (md *model) readScroll() {
 		}
 		md.smallPager.SetLines(stts)
 		md.smallPager.SetCursor(gruid.Point{0, 0})
-		if !md.g.Stats.Lore[md.g.Depth] {
-			md.g.StoryPrint("Read lore message")
-		}
-		md.g.Stats.Lore[md.g.Depth] = true
-		if len(md.g.Stats.Lore) == 4 {
-			AchLoreStudent.Get(md.g)
-		}
-		if len(md.g.Stats.Lore) == len(md.g.Params.Lore) {
-			AchLoremaster.Get(md.g)
-		}
+		md.g.LearnLore()
 	default:
 		md.smallPager.SetBox(&ui.Box{Title: ui.Text("Story Message").WithStyle(st.WithFg(ColorCyan))})
 		stts := []ui.StyledText{}
@@ -650,6 +624,90 @@ func (md *model) readScroll() {
 	}
 }
 
+// LearnLore records that the lore message of the current level has been read.
+func (g *game) LearnLore() {
+	if !g.Stats.Lore[g.Depth] {
+		g.StoryPrint("Read lore message")
+	}
+	g.Stats.Lore[g.Depth] = true
+	g.CheckAchievements(CounterLore, CounterLorePerc)
+}
+
+// GoToStairs moves the player toward the nearest stairs. It returns the
+// position of the stairs, if any.
+func (g *game) GoToStairs() (gruid.Point, error) {
+	stairs := g.StairsSlice()
+	sortedStairs := g.SortedNearestTo(stairs, g.Player.P)
+	if len(sortedStairs) == 0 {
+		return invalidPos, errors.New("You cannot go to any stairs.")
+	}
+	stair := sortedStairs[0]
+	if g.Player.P == stair {
+		return invalidPos, errors.New("You are already on the stairs.")
+	}
+	err := g.SetAutoTarget(stair)
+	if err != nil {
+		return stair, errors.New("There is no safe path to the nearest stairs.")
+	} else if !g.MoveToTarget() {
+		return stair, errors.New("You could not move toward stairs.")
+	}
+	return stair, nil
+}
+
+// Interact performs the interaction with the object at the player's
+// position, for objects that do not require a menu (magaras and scrolls).
+// If the player escapes, the game's depth becomes -1.
+func (g *game) Interact() (again bool, err error) {
+	c := g.Dungeon.Cell(g.Player.P)
+	switch terrain(c) {
+	case StairCell:
+		if terrain(g.Dungeon.Cell(g.Player.P)) == StairCell && g.Objects.Stairs[g.Player.P] != BlockedStair {
+			// TODO: animation
+			//ui.MenuSelectedAnimation(MenuInteract, true)
+			strt := g.Objects.Stairs[g.Player.P]
+			err = g.checkShaedra(strt)
+			if err != nil {
+				break
+			}
+			again = true
+			g.Descend(DescendNormal)
+			//ui.DrawDungeonView(NormalMode)
+		} else if terrain(g.Dungeon.Cell(g.Player.P)) == StairCell && g.Objects.Stairs[g.Player.P] == BlockedStair {
+			err = errors.New("The stairs are blocked by a magical stone barrier energies.")
+		} else {
+			err = errors.New("No stairs here.")
+		}
+	case BarrelCell:
+		//ui.MenuSelectedAnimation(MenuInteract, true)
+		err = g.Rest()
+		//if err != nil {
+		//ui.MenuSelectedAnimation(MenuInteract, false)
+		//}
+	case StoneCell:
+		//ui.MenuSelectedAnimation(MenuInteract, true)
+		err = g.ActivateStone()
+		//if err != nil {
+		//ui.MenuSelectedAnimation(MenuInteract, false)
+		//}
+	case ItemCell:
+		err = g.EquipItem()
+	case LightCell:
+		err = g.ExtinguishFire()
+	case StoryCell:
+		if g.Objects.Story[g.Player.P] == StoryArtifact && !g.LiberatedArtifact {
+			g.PushEventFirst(&playerEvent{Action: StorySequence}, g.Turn)
+			g.LiberatedArtifact = true
+		} else if g.Objects.Story[g.Player.P] == StoryArtifactSealed {
+			err = errors.New("The artifact is protected by a magical stone barrier.")
+		} else {
+			err = errors.New("You cannot interact with anything here.")
+		}
+	default:
+		err = errors.New("You cannot interact with anything here.")
+	}
+	return again, err
+}
+
 type actionError int
 
 const (
@@ -737,19 +795,32 @@ func (md *model) openKeyBindings() {
 	md.menuMode = modeKeys
 }
 
-var menuActions = []action{
-	ActionLogs,
-	ActionMenuCommandHelp,
-	ActionMenuTargetingHelp,
-	ActionSettings,
-	ActionSave,
-	ActionQuit,
+func menuActions() []action {
+	actions := []action{
+		ActionLogs,
+		ActionMenuCommandHelp,
+		ActionMenuTargetingHelp,
+		ActionConducts,
+		ActionAchievements,
+		ActionDailyLedger,
+	}
+	if runtime.GOOS != "js" {
+		// games are not archived in the browser
+		actions = append(actions, ActionMorgue)
+	}
+	return append(actions,
+		ActionCareer,
+		ActionHighScores,
+		ActionSettings,
+		ActionSave,
+		ActionQuit,
+	)
 }
 
 func (md *model) openMenu() {
 	entries := []ui.MenuEntry{}
 	r := 'a'
-	for _, it := range menuActions {
+	for _, it := range menuActions() {
 		entries = append(entries, ui.MenuEntry{
 			Text: ui.Textf("%c - %s", r, it),
 			Keys: []gruid.Key{gruid.Key(r)},
@@ -915,8 +986,7 @@ func (md *model) ExamineHelp() {
 	})
 }
 
-func (md *model) checkShaedra(st stair) (err error) {
-	g := md.g
+func (g *game) checkShaedra(st stair) (err error) {
 	if g.Depth == WinDepth && st == NormalStair && terrain(g.Dungeon.Cell(g.Places.Shaedra)) == StoryCell {
 		err = errors.New("You have to rescue Shaedra first!")
 	}
@@ -0,0 +1,684 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/rl"
+)
+
+// Agent chooses the actions of the player in a headless game, as a bot
+// would.
+type Agent interface {
+	// Act returns the next action, given the player's current observation.
+	Act(obs *observation) action
+}
+
+// evokingAgent is an agent that can evoke magaras: when it returns
+// ActionEvoke, Magara returns the slot of the magara to evoke.
+type evokingAgent interface {
+	Agent
+	Magara() int
+}
+
+// agents contains the available agents by name.
+var agents = map[string]func() Agent{
+	"explorer": func() Agent { return &explorerAgent{} },
+}
+
+// observation contains what the player legitimately knows about the game.
+type observation struct {
+	Depth              int
+	Turn               int
+	P                  gruid.Point // player's position
+	HP                 int
+	HPMax              int
+	MP                 int
+	MPMax              int
+	Bananas            int
+	Map                *dungeon             // known terrain (see Cell)
+	LOS                map[gruid.Point]bool // positions in view
+	Monsters           []monsterObservation // monsters in view
+	LastMonsterKnownAt map[gruid.Point]int
+	Magaras            []magara
+	Inventory          inventory
+	Statuses           map[status]int
+	Err                error // error returned by the previous action, if any
+}
+
+// monsterObservation describes a monster in view.
+type monsterObservation struct {
+	Kind  monsterKind
+	P     gruid.Point
+	Dir   gruid.Point
+	State monsterState
+}
+
+// Cell returns the terrain the player remembers at a given position, and
+// whether the position has been explored at all.
+func (obs *observation) Cell(p gruid.Point) (cell, bool) {
+	if !valid(p) {
+		return WallCell, false
+	}
+	c := obs.Map.Cell(p)
+	return terrain(c), explored(c)
+}
+
+// Observe returns the player's current observation of the game.
+func (g *game) Observe() *observation {
+	obs := &observation{
+		Depth:              g.Depth,
+		Turn:               g.Turn,
+		P:                  g.Player.P,
+		HP:                 g.Player.HP,
+		HPMax:              g.Player.HPMax(),
+		MP:                 g.Player.MP,
+		MPMax:              g.Player.MPMax(),
+		Bananas:            g.Player.Bananas,
+		Map:                &dungeon{Grid: rl.NewGrid(DungeonWidth, DungeonHeight)},
+		LOS:                map[gruid.Point]bool{},
+		LastMonsterKnownAt: map[gruid.Point]int{},
+		Magaras:            append([]magara{}, g.Player.Magaras...),
+		Inventory:          g.Player.Inventory,
+		Statuses:           map[status]int{},
+	}
+	it := g.Dungeon.Grid.Iterator()
+	for it.Next() {
+		c := cell(it.Cell())
+		if !explored(c) {
+			continue
+		}
+		if t, ok := g.TerrainKnowledge[it.P()]; ok {
+			c = t
+		}
+		obs.Map.Grid.Set(it.P(), rl.Cell(c|Explored))
+	}
+	for p, b := range g.Player.LOS {
+		if b {
+			obs.LOS[p] = true
+		}
+	}
+	for _, mons := range g.Monsters {
+		if mons.Exists() && g.Player.Sees(mons.P) {
+			obs.Monsters = append(obs.Monsters, monsterObservation{
+				Kind:  mons.Kind,
+				P:     mons.P,
+				Dir:   mons.Dir,
+				State: mons.State,
+			})
+		}
+	}
+	for p, i := range g.LastMonsterKnownAt {
+		obs.LastMonsterKnownAt[p] = i
+	}
+	for st, n := range g.Player.Statuses {
+		obs.Statuses[st] = n
+	}
+	return obs
+}
+
+// explorerAgent is a simple reference agent: it explores each level, and
+// then goes down the nearest stairs. It uses auto-exploration when no
+// monsters are in view, and explores step by step otherwise, keeping away
+// from monsters. When a monster is hunting the player, it runs to a barrel or
+// to the stairs if it can get there first, or flees, jumping if useful and
+// preferring doors, and it leaves the level as soon as possible. It evokes
+// magaras when cornered, and rests in barrels when hurt.
+type explorerAgent struct {
+	depth    int
+	explored bool                 // no more exploration on this level
+	blocked  map[gruid.Point]bool // stairs known to be blocked
+	hunters  []gruid.Point        // last known positions of hunting monsters
+	hunted   int                  // last turn the player was hunted
+	seen     bool                 // whether hunting monsters are in view
+	escaping int                  // number of consecutive escaping actions
+	bold     int                  // turn until which hunters are ignored
+	evoke    int                  // magara slot for ActionEvoke
+	last     action               // previous action
+	failures int                  // number of consecutive failed actions
+}
+
+const (
+	// agentMemory is the number of turns the explorer agent remembers
+	// hunting monsters that are no longer in view.
+	agentMemory = 10
+	// agentPatience is the maximum number of consecutive actions the
+	// explorer agent spends escaping the same monsters: it then ignores
+	// them for a while, so as not to loop.
+	agentPatience = 50
+	// agentSafeDistance is the distance the explorer agent keeps from
+	// awake monsters, when possible.
+	agentSafeDistance = 3
+)
+
+func (ag *explorerAgent) Act(obs *observation) action {
+	if obs.Depth != ag.depth {
+		ag.depth = obs.Depth
+		ag.explored = false
+		ag.blocked = map[gruid.Point]bool{}
+		ag.hunters = nil
+	}
+	if obs.Err != nil {
+		ag.failures++
+		if c, _ := obs.Cell(obs.P); c == StairCell && ag.last == ActionInteract {
+			ag.blocked[obs.P] = true
+		}
+	} else {
+		ag.failures = 0
+	}
+	ag.last = ag.act(obs)
+	return ag.last
+}
+
+func (ag *explorerAgent) act(obs *observation) action {
+	if ag.failures > 2 {
+		// avoid looping on failing actions
+		return ActionWaitTurn
+	}
+	hunters := []gruid.Point{}
+	for _, m := range obs.Monsters {
+		if m.State == Hunting {
+			hunters = append(hunters, m.P)
+		}
+	}
+	ag.seen = len(hunters) > 0
+	if ag.seen {
+		ag.hunters = hunters
+		ag.hunted = obs.Turn
+	} else if len(ag.hunters) > 0 && obs.Turn-ag.hunted < agentMemory {
+		hunters = ag.hunters
+	}
+	if len(hunters) > 0 {
+		ag.escaping++
+		if ag.escaping > agentPatience {
+			ag.bold = obs.Turn + agentPatience
+		}
+	} else {
+		ag.escaping = 0
+	}
+	if obs.Turn < ag.bold {
+		hunters = nil
+		ag.escaping = 0
+	}
+	failed := obs.Err != nil && ag.last == ActionInteract
+	switch c, _ := obs.Cell(obs.P); c {
+	case BarrelCell:
+		if len(hunters) > 0 {
+			// monsters cannot attack the player in a barrel
+			return ActionWaitTurn
+		}
+		if ag.hurt(obs) && obs.Bananas > 0 && !failed {
+			return ActionInteract
+		}
+	case StairCell:
+		if (ag.explored || ag.hunters != nil) && !ag.blocked[obs.P] {
+			return ActionInteract
+		}
+	}
+	if len(hunters) > 0 {
+		a, cornered := ag.escape(obs, hunters)
+		if i, ok := ag.magara(obs); ok && cornered {
+			ag.evoke = i
+			return ActionEvoke
+		}
+		return a
+	}
+	if ag.hurt(obs) && obs.Bananas > 0 {
+		if a := ag.stepTo(obs, ag.isBarrel); a != ActionNone {
+			return a
+		}
+	}
+	if ag.hunters != nil {
+		// the level is dangerous: leave it if possible
+		if a := ag.stepTo(obs, ag.isStair); a != ActionNone {
+			return a
+		}
+	}
+	if !ag.explored {
+		if len(obs.Monsters) == 0 && !(obs.Err != nil && ag.last == ActionExplore) {
+			return ActionExplore
+		}
+		if a := ag.exploreStep(obs); a != ActionNone {
+			return a
+		}
+		if len(obs.Monsters) > 0 {
+			// unexplored places may be behind monsters
+			return ag.wander(obs)
+		}
+		ag.explored = true
+	}
+	if a := ag.stepTo(obs, ag.isStair); a != ActionNone {
+		return a
+	}
+	return ag.wander(obs)
+}
+
+// Magara returns the slot of the magara to evoke for ActionEvoke.
+func (ag *explorerAgent) Magara() int {
+	return ag.evoke
+}
+
+// magara returns the slot of a magara that may help escaping hunting
+// monsters, if any.
+func (ag *explorerAgent) magara(obs *observation) (int, bool) {
+	if ag.last == ActionEvoke {
+		// evoking did not help, or failed
+		return 0, false
+	}
+	for i, mag := range obs.Magaras {
+		if mag.Charges > 0 && escapeMagaras[mag.Kind] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// escapeMagaras are the magaras that the explorer agent uses for escaping
+// monsters.
+var escapeMagaras = map[magaraKind]bool{
+	BlinkMagara:         true,
+	TeleportMagara:      true,
+	SwiftnessMagara:     true,
+	FogMagara:           true,
+	ShadowsMagara:       true,
+	ConfusionMagara:     true,
+	ParalysisMagara:     true,
+	SleepingMagara:      true,
+	TeleportOtherMagara: true,
+	SwappingMagara:      true,
+	ObstructionMagara:   true,
+	LignificationMagara: true,
+	TransparencyMagara:  true,
+	DisguiseMagara:      true,
+}
+
+// hurt reports whether the player would benefit from resting.
+func (ag *explorerAgent) hurt(obs *observation) bool {
+	return obs.HP < obs.HPMax
+}
+
+func (ag *explorerAgent) isBarrel(obs *observation, p gruid.Point) bool {
+	c, _ := obs.Cell(p)
+	return c == BarrelCell
+}
+
+func (ag *explorerAgent) isStair(obs *observation, p gruid.Point) bool {
+	c, _ := obs.Cell(p)
+	return c == StairCell && !ag.blocked[p]
+}
+
+var agentDirs = [...]action{ActionW, ActionS, ActionN, ActionE}
+
+// passable reports whether the player can move to a position, as far as the
+// agent knows.
+func (ag *explorerAgent) passable(obs *observation, p gruid.Point) bool {
+	c, ok := obs.Cell(p)
+	if !ok || !c.IsPlayerPassable() {
+		return false
+	}
+	for _, m := range obs.Monsters {
+		if m.P == p {
+			return false
+		}
+	}
+	return true
+}
+
+// safe reports whether the player can move to a position without getting
+// next to a monster in view.
+func (ag *explorerAgent) safe(obs *observation, p gruid.Point) bool {
+	if !ag.passable(obs, p) {
+		return false
+	}
+	for _, m := range obs.Monsters {
+		d := 1
+		if m.State != Resting {
+			d = agentSafeDistance
+		}
+		if distance(p, m.P) <= d && p != obs.P {
+			return false
+		}
+	}
+	return true
+}
+
+// agentPath contains the results of a breadth first search: distances from
+// the sources and, for a single source, the first move toward each
+// position.
+type agentPath struct {
+	dist    []int         // distances, plus one (zero means not reached)
+	first   []action      // first moves
+	reached []gruid.Point // reached positions, by increasing distance
+}
+
+// Dist returns the distance to a position, and whether it was reached.
+func (ap *agentPath) Dist(p gruid.Point) (int, bool) {
+	if !valid(p) || ap.dist[idx(p)] == 0 {
+		return 0, false
+	}
+	return ap.dist[idx(p)] - 1, true
+}
+
+// search performs a breadth first search from the given sources, through
+// passable positions.
+func (ag *explorerAgent) search(obs *observation, sources []gruid.Point, passable func(*observation, gruid.Point) bool) *agentPath {
+	ap := &agentPath{
+		dist:  make([]int, DungeonWidth*DungeonHeight),
+		first: make([]action, DungeonWidth*DungeonHeight),
+	}
+	for _, p := range sources {
+		if valid(p) && ap.dist[idx(p)] == 0 {
+			ap.dist[idx(p)] = 1
+			ap.reached = append(ap.reached, p)
+		}
+	}
+	for i := 0; i < len(ap.reached); i++ {
+		p := ap.reached[i]
+		for _, a := range agentDirs {
+			q := p.Add(keyToDir(a))
+			if !valid(q) || ap.dist[idx(q)] > 0 || !passable(obs, q) {
+				continue
+			}
+			ap.dist[idx(q)] = ap.dist[idx(p)] + 1
+			if ap.dist[idx(p)] == 1 {
+				ap.first[idx(q)] = a
+			} else {
+				ap.first[idx(q)] = ap.first[idx(p)]
+			}
+			ap.reached = append(ap.reached, q)
+		}
+	}
+	return ap
+}
+
+// stepTo returns the first move toward the nearest position satisfying a
+// predicate, avoiding monsters, or ActionNone if there is none.
+func (ag *explorerAgent) stepTo(obs *observation, target func(*observation, gruid.Point) bool) action {
+	ap := ag.search(obs, []gruid.Point{obs.P}, ag.safe)
+	for _, p := range ap.reached[1:] {
+		if target(obs, p) {
+			return ap.first[idx(p)]
+		}
+	}
+	return ActionNone
+}
+
+// monsterPassable reports whether monsters can move to a position, as far as
+// the agent knows.
+func monsterPassable(obs *observation, p gruid.Point) bool {
+	c, ok := obs.Cell(p)
+	return ok && c.IsDoorPassable()
+}
+
+// refuge reports whether a position is a place where the player can escape
+// hunting monsters: stairs, or a barrel when not seen.
+func (ag *explorerAgent) refuge(obs *observation, p gruid.Point) bool {
+	return ag.isStair(obs, p) || ag.isBarrel(obs, p) && !ag.seen
+}
+
+// escapeMove is a possible move for escaping, with the position where the
+// player lands.
+type escapeMove struct {
+	a action
+	p gruid.Point
+}
+
+// escapeMoves returns the possible moves for escaping: normal moves, and
+// jumps over adjacent monsters or against walls.
+func (ag *explorerAgent) escapeMoves(obs *observation) []escapeMove {
+	ems := []escapeMove{{ActionWaitTurn, obs.P}}
+	jump := obs.Statuses[StatusExhausted] == 0 && obs.Statuses[StatusLignification] == 0
+	if c, _ := obs.Cell(obs.P); c.IsEnclosing() {
+		jump = false
+	}
+	for _, a := range agentDirs {
+		dir := keyToDir(a)
+		q := obs.P.Add(dir)
+		if !valid(q) {
+			continue
+		}
+		c, _ := obs.Cell(q)
+		switch {
+		case ag.monsterAt(obs, q):
+			if !jump {
+				continue
+			}
+			for ag.monsterAt(obs, q) {
+				q = q.Add(dir)
+			}
+			if ag.passable(obs, q) {
+				ems = append(ems, escapeMove{a, q})
+			}
+		case c.IsJumpPropulsion():
+			if !jump {
+				continue
+			}
+			q = obs.P
+			count := 0
+			for count < 3 {
+				p := q.Sub(dir)
+				if c, ok := obs.Cell(p); !ok || !c.IsJumpPassable() || ag.monsterAt(obs, p) {
+					break
+				}
+				q = p
+				count++
+			}
+			for count > 1 && !ag.passable(obs, q) {
+				q = q.Add(dir)
+				count--
+			}
+			if count >= 2 {
+				ems = append(ems, escapeMove{a, q})
+			}
+		case ag.passable(obs, q) && (c != BarrelCell || !ag.seen):
+			ems = append(ems, escapeMove{a, q})
+		}
+	}
+	return ems
+}
+
+// monsterAt reports whether there is a monster in view at a position.
+func (ag *explorerAgent) monsterAt(obs *observation, p gruid.Point) bool {
+	for _, m := range obs.Monsters {
+		if m.P == p {
+			return true
+		}
+	}
+	return false
+}
+
+// monsterDist returns the distance from hunting monsters to a position, given
+// their search results. Positions that monsters cannot enter are still within
+// reach of attacks from next positions.
+func monsterDist(mp *agentPath, p gruid.Point) int {
+	if d, ok := mp.Dist(p); ok {
+		return d
+	}
+	d := DungeonWidth + DungeonHeight
+	for _, a := range agentDirs {
+		if dq, ok := mp.Dist(p.Add(keyToDir(a))); ok && dq+1 < d {
+			d = dq + 1
+		}
+	}
+	return d
+}
+
+// escape returns a move for escaping hunting monsters: toward a refuge that
+// the player can reach before them, or else away from them, preferring doors,
+// which block their line of sight. Jumps are considered too. It also reports
+// whether the player is cornered, that is, whether monsters will still be
+// next to the player after the move.
+func (ag *explorerAgent) escape(obs *observation, hunters []gruid.Point) (action, bool) {
+	mp := ag.search(obs, hunters, monsterPassable)
+	score := func(p gruid.Point) int {
+		pp := ag.search(obs, []gruid.Point{p}, ag.passable)
+		for _, q := range pp.reached {
+			if d, _ := pp.Dist(q); ag.refuge(obs, q) && monsterDist(mp, q) > d {
+				return 1<<20 - d
+			}
+		}
+		// get away, toward the farthest place from the monsters that
+		// the player can reach before them
+		far := 0
+		for _, q := range pp.reached {
+			d, _ := pp.Dist(q)
+			if md := monsterDist(mp, q); md > d && md > far {
+				far = md
+			}
+		}
+		s := monsterDist(mp, p)*(DungeonWidth+DungeonHeight)*2 + far*2
+		if c, _ := obs.Cell(p); c == DoorCell {
+			s++
+		}
+		return s
+	}
+	best := ActionWaitTurn
+	bestScore := -1
+	cornered := true
+	for _, em := range ag.escapeMoves(obs) {
+		if s := score(em.p); s > bestScore {
+			best = em.a
+			bestScore = s
+			cornered = s < 1<<19 && monsterDist(mp, em.p) <= 1
+		}
+	}
+	return best, cornered
+}
+
+// exploreStep returns the first move toward the nearest known position next
+// to an unexplored one, keeping away from monsters, or ActionNone if there is
+// none.
+func (ag *explorerAgent) exploreStep(obs *observation) action {
+	return ag.stepTo(obs, func(obs *observation, p gruid.Point) bool {
+		for _, a := range agentDirs {
+			q := p.Add(keyToDir(a))
+			if _, ok := obs.Cell(q); !ok && valid(q) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// wander returns a move in a passable direction, which changes regularly with
+// time.
+func (ag *explorerAgent) wander(obs *observation) action {
+	for i := range agentDirs {
+		a := agentDirs[(obs.Turn/50+i)%len(agentDirs)]
+		if ag.passable(obs, obs.P.Add(keyToDir(a))) {
+			return a
+		}
+	}
+	return ActionWaitTurn
+}
+
+// maxBotActions is the maximum number of actions in a bot game: the game is
+// considered stuck afterwards.
+const maxBotActions = 5000
+
+// botReport summarizes the games played by an agent.
+type botReport struct {
+	Agent        string
+	Games        int
+	Wins         int
+	Stuck        int            // games stopped after maxBotActions
+	Depths       int            // sum of the maximum depths reached
+	Deaths       map[string]int // deaths by cause
+	Achievements map[achievement]int
+}
+
+// playBot plays a headless game with a given seed, driven by an agent, and
+// returns the game.
+func playBot(ag Agent, seed int64) (*game, bool) {
+	s := newSim(seed)
+	var err error
+	for i := 0; i < maxBotActions; i++ {
+		if s.Over() {
+			return s.g, true
+		}
+		obs := s.g.Observe()
+		obs.Err = err
+		a := ag.Act(obs)
+		if eag, ok := ag.(evokingAgent); ok && a == ActionEvoke {
+			err = s.Evoke(eag.Magara())
+		} else {
+			err = s.Do(a)
+		}
+	}
+	return s.g, s.Over()
+}
+
+// playBots plays n games with the named agent, with seeds seed, seed+1, and
+// so on.
+func playBots(name string, n int, seed int64) (*botReport, error) {
+	newAgent, ok := agents[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent: %s", name)
+	}
+	r := &botReport{
+		Agent:        name,
+		Deaths:       map[string]int{},
+		Achievements: map[achievement]int{},
+	}
+	for i := 0; i < n; i++ {
+		g, over := playBot(newAgent(), seed+int64(i))
+		r.Games++
+		switch {
+		case !over:
+			r.Stuck++
+		case g.Player.HP <= 0:
+			cause := g.Stats.KilledBy
+			if cause == "" {
+				cause = "unknown"
+			}
+			r.Deaths[cause]++
+		default:
+			r.Wins++
+		}
+		r.Depths += max(g.Depth, g.ExploredLevels)
+		for ach := range g.Stats.Achievements {
+			r.Achievements[ach]++
+		}
+	}
+	return r, nil
+}
+
+// Write writes a human-readable summary of the report.
+func (r *botReport) Write(w io.Writer) {
+	fmt.Fprintf(w, "Agent: %s\n", r.Agent)
+	fmt.Fprintf(w, "Games: %d\n", r.Games)
+	if r.Games == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Win rate: %.1f%%\n", float64(r.Wins)*100/float64(r.Games))
+	fmt.Fprintf(w, "Average depth: %.2f\n", float64(r.Depths)/float64(r.Games))
+	if r.Stuck > 0 {
+		fmt.Fprintf(w, "Stuck games: %d\n", r.Stuck)
+	}
+	fmt.Fprintf(w, "\nDeaths:\n")
+	causes := []string{}
+	for cause := range r.Deaths {
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		ci, cj := causes[i], causes[j]
+		return r.Deaths[ci] > r.Deaths[cj] || r.Deaths[ci] == r.Deaths[cj] && ci < cj
+	})
+	for _, cause := range causes {
+		fmt.Fprintf(w, "%5d %s\n", r.Deaths[cause], cause)
+	}
+	fmt.Fprintf(w, "\nAchievements:\n")
+	achs := []achievement{}
+	for ach := range r.Achievements {
+		achs = append(achs, ach)
+	}
+	sort.Slice(achs, func(i, j int) bool {
+		ai, aj := achs[i], achs[j]
+		return r.Achievements[ai] > r.Achievements[aj] || r.Achievements[ai] == r.Achievements[aj] && ai < aj
+	})
+	for _, ach := range achs {
+		fmt.Fprintf(w, "%5d %s\n", r.Achievements[ach], ach)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestObserve(t *testing.T) {
+	s := newSim(2)
+	s.Do(ActionExplore)
+	g := s.g
+	obs := g.Observe()
+	it := g.Dungeon.Grid.Iterator()
+	for it.Next() {
+		_, known := obs.Cell(it.P())
+		if known != explored(cell(it.Cell())) {
+			t.Errorf("bad knowledge at %v", it.P())
+		}
+	}
+	for _, m := range obs.Monsters {
+		if !g.Player.Sees(m.P) {
+			t.Errorf("monster not in view at %v", m.P)
+		}
+	}
+	obs.Statuses[StatusSwift] = 10
+	if g.Player.Statuses[StatusSwift] == 10 {
+		t.Errorf("observation shares game state")
+	}
+}
+
+func TestPlayBots(t *testing.T) {
+	const n = 5
+	r, err := playBots("explorer", n, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deaths := 0
+	for _, k := range r.Deaths {
+		deaths += k
+	}
+	if r.Games != n || r.Wins+r.Stuck+deaths != n {
+		t.Errorf("bad report: %+v", r)
+	}
+	if r.Depths <= n {
+		t.Errorf("bots do not get past depth 1: %+v", r)
+	}
+	if _, err := playBots("unknown", n, 1); err == nil {
+		t.Errorf("no error for unknown agent")
+	}
+}
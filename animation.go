@@ -32,6 +32,12 @@ type AnimFrame struct {
 
 type msgAnim int
 
+// animations reports whether animations should be played. Headless games
+// have no model, and thus no animations.
+func (md *model) animations() bool {
+	return md != nil && !DisableAnimations
+}
+
 func (md *model) initAnimations() {
 	gd := md.gd.Slice(md.gd.Range().Shift(0, 2, 0, -1))
 	max := gd.Size()
@@ -125,7 +131,7 @@ func (a *Animations) Frame(d time.Duration) {
 }
 
 func (md *model) SwappingAnimation(mp, pp gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -141,7 +147,7 @@ func (md *model) SwappingAnimation(mp, pp gruid.Point) {
 }
 
 func (md *model) TeleportAnimation(from, to gruid.Point, showto bool) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -166,7 +172,7 @@ const (
 )
 
 func (md *model) MonsterProjectileAnimation(ray []gruid.Point, r rune, fg gruid.Color) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -210,7 +216,7 @@ func (md *model) ExplosionDrawAt(p gruid.Point, fg gruid.Color) {
 }
 
 func (md *model) NoiseAnimation(noises []gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.LOSWavesAnimation(DefaultLOSRange, WaveMagicNoise, md.g.Player.P)
@@ -230,7 +236,7 @@ func (md *model) NoiseAnimation(noises []gruid.Point) {
 }
 
 func (md *model) ExplosionAnimation(es explosionStyle, p gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -278,6 +284,9 @@ func (g *game) Waves(maxCost int, ws wavestyle, center gruid.Point) (dists []int
 }
 
 func (md *model) LOSWavesAnimation(r int, ws wavestyle, center gruid.Point) {
+	if !md.animations() {
+		return
+	}
 	dists, cdists := md.g.Waves(r, ws, center)
 	for _, d := range dists {
 		wave := cdists[d]
@@ -300,7 +309,7 @@ const (
 )
 
 func (md *model) WaveAnimation(wave []int, ws wavestyle) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -341,7 +350,7 @@ func (md *model) WaveAnimation(wave []int, ws wavestyle) {
 }
 
 func (md *model) WallExplosionAnimation(p gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -363,7 +372,7 @@ const (
 )
 
 func (md *model) BeamsAnimation(ray []gruid.Point, bs beamstyle) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -393,7 +402,7 @@ func (md *model) BeamsAnimation(ray []gruid.Point, bs beamstyle) {
 }
 
 func (md *model) SlowingMagaraAnimation(ray []gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -429,7 +438,7 @@ func (md *model) ProjectileSymbol(dir gruid.Point) (r rune) {
 }
 
 func (md *model) MonsterJavelinAnimation(ray []gruid.Point, hit bool) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -446,7 +455,7 @@ func (md *model) MonsterJavelinAnimation(ray []gruid.Point, hit bool) {
 }
 
 func (md *model) WoundedAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -461,7 +470,7 @@ func (md *model) WoundedAnimation() {
 }
 
 func (md *model) PlayerGoodEffectAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -476,7 +485,7 @@ func (md *model) PlayerGoodEffectAnimation() {
 }
 
 func (md *model) StatusEndAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -487,7 +496,7 @@ func (md *model) StatusEndAnimation() {
 }
 
 func (md *model) EffectAtPPAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -498,7 +507,7 @@ func (md *model) EffectAtPPAnimation() {
 }
 
 func (md *model) FoundFakeStairsAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	g := md.g
@@ -508,7 +517,7 @@ func (md *model) FoundFakeStairsAnimation() {
 }
 
 func (md *model) MusicAnimation(p gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	// TODO: not convinced by this animation
@@ -521,7 +530,7 @@ func (md *model) MusicAnimation(p gruid.Point) {
 }
 
 func (md *model) PushAnimation(path []gruid.Point) {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	if len(path) == 0 {
@@ -539,7 +548,7 @@ func (md *model) PushAnimation(path []gruid.Point) {
 }
 
 func (md *model) MagicMappingAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	md.startAnimSeq()
@@ -547,7 +556,7 @@ func (md *model) MagicMappingAnimation() {
 }
 
 func (md *model) AbyssFallAnimation() {
-	if DisableAnimations {
+	if !md.animations() {
 		return
 	}
 	gd := rl.NewGrid(DungeonWidth, DungeonHeight)
@@ -586,37 +595,30 @@ func (md *model) FreeingShaedra() {
 		g.PrintStyled("Shaedra: “Oh, it's you, Syu! Let's flee with Marevor's magara!”", logSpecial)
 		md.confirm = true
 	case 1:
-		if !DisableAnimations {
+		if md.animations() {
 			md.startAnimSeq()
 			_, _, bg := md.positionDrawing(g.Places.Monolith)
 			md.anims.Draw(g.Places.Monolith, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Frame(AnimDurMediumLong)
-		}
-		g.Objects.Stairs[g.Places.Monolith] = WinStair
-		g.Dungeon.SetCell(g.Places.Monolith, StairCell)
-		if !DisableAnimations {
 			md.startAnimSeq()
 			md.anims.Frame(AnimDurMediumLong)
-			_, _, bg := md.positionDrawing(g.Places.Marevor)
+			_, _, bg = md.positionDrawing(g.Places.Marevor)
 			md.anims.Draw(g.Places.Marevor, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Frame(AnimDurMediumLong)
 		}
-		g.Objects.Story[g.Places.Marevor] = StoryMarevor
+		g.OpenEscapePortal()
 		g.PrintStyled("Marevor: “And what about the mission? Take that magara!”", logSpecial)
 		g.PrintStyled("Shaedra: “Pff, don't be reckless!”", logSpecial)
 		g.PrintStyled("[(x) to continue]", logConfirm)
 	case 2:
-		if !DisableAnimations {
+		if md.animations() {
 			md.startAnimSeq()
 			_, _, bg := md.positionDrawing(g.Places.Marevor)
 			md.anims.Draw(g.Places.Marevor, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Draw(g.Places.Shaedra, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Frame(AnimDurMediumLong)
 		}
-		g.Dungeon.SetCell(g.Places.Shaedra, GroundCell)
-		g.Dungeon.SetCell(g.Places.Marevor, ScrollCell)
-		g.Objects.Scrolls[g.Places.Marevor] = ScrollExtended
-		g.RescuedShaedra()
+		g.FreeShaedra()
 		md.story = 0
 		md.mode = modeNormal
 		return
@@ -624,15 +626,51 @@ func (md *model) FreeingShaedra() {
 	md.story++
 }
 
+// storyHeadless plays the story sequence of the current level at once, for
+// games without user interface.
+func (g *game) storyHeadless() {
+	switch g.Depth {
+	case WinDepth:
+		g.OpenEscapePortal()
+		g.FreeShaedra()
+	case MaxDepth:
+		g.Dungeon.SetCell(g.Places.Artifact, GroundCell)
+		g.OpenEscapePortal()
+		g.RetrieveArtifact()
+	}
+}
+
+// OpenEscapePortal transforms the monolith into the escape portal, and makes
+// Marevor appear.
+func (g *game) OpenEscapePortal() {
+	g.Objects.Stairs[g.Places.Monolith] = WinStair
+	g.Dungeon.SetCell(g.Places.Monolith, StairCell)
+	g.Objects.Story[g.Places.Marevor] = StoryMarevor
+}
+
+func (g *game) FreeShaedra() {
+	g.Dungeon.SetCell(g.Places.Shaedra, GroundCell)
+	g.Dungeon.SetCell(g.Places.Marevor, ScrollCell)
+	g.Objects.Scrolls[g.Places.Marevor] = ScrollExtended
+	g.RescuedShaedra()
+}
+
+func (g *game) RetrieveArtifact() {
+	g.Dungeon.SetCell(g.Places.Marevor, GroundCell)
+	g.StoryEntry(timelineEntry{Kind: TimelineRescue, Name: "Artifact", Text: "Retrieved the Artifact"})
+	AchRetrievedArtifact.Get(g)
+}
+
 func (g *game) RescuedShaedra() {
 	g.Player.Magaras = append(g.Player.Magaras, magara{})
 	g.Player.Inventory.Misc = NoItem
 	g.PrintStyled("You equip the new magara in the artifact's old place.", logSpecial)
-	if RandInt(2) == 0 {
+	if g.randInt(2) == 0 {
 		g.Player.Magaras[len(g.Player.Magaras)-1] = magara{Kind: DispersalMagara, Charges: DispersalMagara.DefaultCharges()}
 	} else {
 		g.Player.Magaras[len(g.Player.Magaras)-1] = magara{Kind: DelayedOricExplosionMagara, Charges: DelayedOricExplosionMagara.DefaultCharges()}
 	}
+	g.StoryEntry(timelineEntry{Kind: TimelineRescue, Name: "Shaedra", Text: "Rescued Shaedra"})
 	AchRescuedShaedra.Get(g)
 }
 
@@ -645,27 +683,22 @@ func (md *model) TakingArtifact() {
 		md.confirm = true
 	case 1:
 		g.Dungeon.SetCell(g.Places.Artifact, GroundCell)
-		if !DisableAnimations {
+		if md.animations() {
 			md.startAnimSeq()
 			_, _, bg := md.positionDrawing(g.Places.Monolith)
 			md.anims.Draw(g.Places.Monolith, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Frame(AnimDurMediumLong)
-		}
-		g.Objects.Stairs[g.Places.Monolith] = WinStair
-		g.Dungeon.SetCell(g.Places.Monolith, StairCell)
-		if !DisableAnimations {
 			md.startAnimSeq()
-			_, _, bg := md.positionDrawing(g.Places.Marevor)
+			_, _, bg = md.positionDrawing(g.Places.Marevor)
 			md.anims.Draw(g.Places.Marevor, 'Φ', ColorFgMagicPlace, bg)
 			md.anims.Frame(AnimDurMediumLong)
 		}
-		g.Objects.Story[g.Places.Marevor] = StoryMarevor
+		g.OpenEscapePortal()
 		g.PrintStyled("Marevor: “Great! Let's escape and find some bones to celebrate!”", logSpecial)
 		g.PrintStyled("Syu: “Sorry, but I prefer bananas!”", logSpecial)
 		g.PrintStyled("[(x) to continue]", logConfirm)
 	case 2:
-		g.Dungeon.SetCell(g.Places.Marevor, GroundCell)
-		AchRetrievedArtifact.Get(g)
+		g.RetrieveArtifact()
 		md.story = 0
 		md.mode = modeNormal
 		return
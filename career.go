@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// career contains statistics accumulated over all the finished games played
+// with a given data directory. Games in wizard mode are not counted.
+// Monster kinds, magaras and achievements are recorded by name, so that the
+// career survives changes in their numbering.
+type career struct {
+	Runs         int
+	Wins         int
+	Shaedra      int               // games in which Shaedra was rescued
+	Artifacts    int               // games in which the Artifact was recovered
+	KilledBy     map[string]int    // deaths by cause
+	DeathDepths  map[int]int       // deaths by depth
+	MagaraUses   map[string]int    // magara evocations by magara name
+	Achievements map[string]string // date each achievement was first earned
+}
+
+// Record adds a finished game to the career statistics, given the date it
+// ended.
+func (c *career) Record(g *game, date string) {
+	if c.KilledBy == nil {
+		c.KilledBy = map[string]int{}
+	}
+	if c.DeathDepths == nil {
+		c.DeathDepths = map[int]int{}
+	}
+	if c.MagaraUses == nil {
+		c.MagaraUses = map[string]int{}
+	}
+	if c.Achievements == nil {
+		c.Achievements = map[string]string{}
+	}
+	c.Runs++
+	if g.Player.HP > 0 && g.Depth == -1 {
+		c.Wins++
+	}
+	if g.LiberatedShaedra {
+		c.Shaedra++
+	}
+	if g.LiberatedArtifact {
+		c.Artifacts++
+	}
+	if g.Player.HP <= 0 {
+		cause := g.Stats.KilledBy
+		if cause == "" {
+			cause = "unknown"
+		}
+		c.KilledBy[cause]++
+		c.DeathDepths[g.Depth]++
+	}
+	for mk, n := range g.Stats.UsedMagaras {
+		if n > 0 {
+			c.MagaraUses[magara{Kind: mk}.String()] += n
+		}
+	}
+	for ach := range g.Stats.Achievements {
+		if _, ok := c.Achievements[string(ach)]; !ok {
+			c.Achievements[string(ach)] = date
+		}
+	}
+}
+
+// RecordCareer adds the finished game to the career statistics.
+func (g *game) RecordCareer(t time.Time) error {
+	if g.Wizard {
+		return nil
+	}
+	c, err := LoadCareer()
+	if err != nil {
+		return err
+	}
+	c.Record(g, t.Format("2006-01-02"))
+	return SaveCareer(c)
+}
+
+// sortedCounts returns the keys of a map of counts, by decreasing count and
+// then by name.
+func sortedCounts(counts map[string]int) []string {
+	keys := []string{}
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		return counts[ki] > counts[kj] || counts[ki] == counts[kj] && ki < kj
+	})
+	return keys
+}
+
+// careerPage is a page of the career statistics screen.
+type careerPage struct {
+	Title string
+	Lines []ui.StyledText
+}
+
+// Pages returns the career statistics as pages.
+func (c *career) Pages() []careerPage {
+	head := gruid.Style{}.WithFg(ColorCyan)
+	earned := 0
+	for _, def := range achievementGoals {
+		if _, ok := c.Achievements[string(def.Ach)]; ok {
+			earned++
+		}
+	}
+	summary := careerPage{Title: "Summary"}
+	pct := func(n int) int {
+		if c.Runs == 0 {
+			return 0
+		}
+		return n * 100 / c.Runs
+	}
+	summary.Lines = append(summary.Lines,
+		ui.Textf(" Finished games:       %5d", c.Runs),
+		ui.Textf(" Escapes:              %5d (%d%%)", c.Wins, pct(c.Wins)),
+		ui.Textf(" Shaedra rescued:      %5d (%d%%)", c.Shaedra, pct(c.Shaedra)),
+		ui.Textf(" Artifact recovered:   %5d (%d%%)", c.Artifacts, pct(c.Artifacts)),
+		ui.Textf(" Achievements earned:  %5d/%d", earned, len(achievementGoals)),
+	)
+
+	deaths := careerPage{Title: "Deaths"}
+	deaths.Lines = append(deaths.Lines, ui.Text(" By cause:").WithStyle(head))
+	if len(c.KilledBy) == 0 {
+		deaths.Lines = append(deaths.Lines, ui.Text("   no deaths yet"))
+	}
+	for _, cause := range sortedCounts(c.KilledBy) {
+		deaths.Lines = append(deaths.Lines, ui.Textf("   %5d %s", c.KilledBy[cause], cause))
+	}
+	deaths.Lines = append(deaths.Lines, ui.Text(""), ui.Text(" By depth:").WithStyle(head))
+	for depth := 1; depth <= MaxDepth; depth++ {
+		if n := c.DeathDepths[depth]; n > 0 {
+			deaths.Lines = append(deaths.Lines, ui.Textf("   %5d depth %d", n, depth))
+		}
+	}
+
+	magaras := careerPage{Title: "Magaras"}
+	if len(c.MagaraUses) == 0 {
+		magaras.Lines = append(magaras.Lines, ui.Text(" No magara evoked yet."))
+	}
+	for _, name := range sortedCounts(c.MagaraUses) {
+		magaras.Lines = append(magaras.Lines, ui.Textf(" %5d %s", c.MagaraUses[name], name))
+	}
+
+	achs := careerPage{Title: "Achievements"}
+	for _, def := range achievementGoals {
+		ach := def.Ach
+		if date, ok := c.Achievements[string(ach)]; ok {
+			achs.Lines = append(achs.Lines, ui.Textf(" %-32s %s", ach, date).WithStyle(gruid.Style{}.WithFg(ColorYellow)))
+		} else {
+			achs.Lines = append(achs.Lines, ui.Textf(" %-32s not yet earned", ach))
+		}
+	}
+	return []careerPage{summary, deaths, magaras, achs}
+}
+
+func (md *model) recordCareer() {
+	err := md.g.RecordCareer(md.now())
+	if err != nil {
+		md.g.PrintfStyled("Error recording career statistics: %v", logError, err)
+	}
+}
+
+func (md *model) openCareer() {
+	c, err := LoadCareer()
+	if err != nil {
+		md.g.PrintfStyled("Error loading career statistics: %v", logError, err)
+		return
+	}
+	md.careerPages = c.Pages()
+	md.careerPage = 0
+	md.showCareerPage()
+}
+
+// showCareerPage shows the current career statistics page in the pager.
+func (md *model) showCareerPage() {
+	p := md.careerPages[md.careerPage]
+	md.pagerMode = modeCareer
+	md.mode = modePager
+	title := fmt.Sprintf(" Career: %s (%d/%d, < > or tab to change page) ", p.Title, md.careerPage+1, len(md.careerPages))
+	md.pager.SetBox(&ui.Box{Title: ui.Text(title).WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	md.pager.SetLines(p.Lines)
+	md.pager.SetCursor(gruid.Point{0, 0})
+}
+
+// updateCareerPage changes the career statistics page on page keys.
+func (md *model) updateCareerPage(msg gruid.Msg) {
+	kd, ok := msg.(gruid.MsgKeyDown)
+	if !ok {
+		return
+	}
+	n := len(md.careerPages)
+	switch kd.Key {
+	case ">", gruid.KeyTab:
+		md.careerPage = (md.careerPage + 1) % n
+	case "<":
+		md.careerPage = (md.careerPage + n - 1) % n
+	default:
+		return
+	}
+	md.showCareerPage()
+}
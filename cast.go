@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// castIdleTimeLimit is the idle time limit in seconds suggested to players of
+// exported cast files: long pauses in a replay, such as between sessions, are
+// kept in the file, but shortened when played.
+const castIdleTimeLimit = 2
+
+// castHeader is the header of an asciinema v2 cast file.
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// castWriter renders frames as terminal output for a cast file, using the
+// xterm 256-color palette of the terminal version.
+type castWriter struct {
+	w     io.Writer
+	buf   bytes.Buffer
+	start time.Time
+	p     gruid.Point // cursor position
+	style gruid.Style // current style
+	init  bool        // style has been set
+}
+
+// WriteCast converts the frames of a replay into an asciinema v2 cast file,
+// keeping their original timing.
+func WriteCast(fd *gruid.FrameDecoder, w io.Writer, title string) error {
+	var frame gruid.Frame
+	if err := fd.Decode(&frame); err != nil {
+		if err == io.EOF {
+			return errors.New("empty replay")
+		}
+		return err
+	}
+	cw := &castWriter{w: w, start: frame.Time}
+	hdr := castHeader{
+		Version:       2,
+		Width:         frame.Width,
+		Height:        frame.Height,
+		Timestamp:     frame.Time.Unix(),
+		IdleTimeLimit: castIdleTimeLimit,
+		Title:         title,
+		Env:           map[string]string{"TERM": "xterm-256color"},
+	}
+	data, err := json.Marshal(hdr)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return err
+	}
+	// hide cursor and clear screen
+	cw.buf.WriteString("\x1b[?25l\x1b[2J")
+	for {
+		cw.frame(frame)
+		if err := cw.flush(frame.Time); err != nil {
+			return err
+		}
+		err := fd.Decode(&frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	cw.buf.WriteString("\x1b[0m\x1b[?25h")
+	return cw.flush(frame.Time)
+}
+
+// frame renders the cell changes of a frame.
+func (cw *castWriter) frame(frame gruid.Frame) {
+	for _, fc := range frame.Cells {
+		if fc.P != cw.p {
+			fmt.Fprintf(&cw.buf, "\x1b[%d;%dH", fc.P.Y+1, fc.P.X+1)
+		}
+		if !cw.init || fc.Cell.Style != cw.style {
+			cw.setStyle(fc.Cell.Style)
+		}
+		r := fc.Cell.Rune
+		if r == 0 {
+			r = ' '
+		}
+		cw.buf.WriteRune(r)
+		cw.p = fc.P.Add(gruid.Point{1, 0})
+	}
+}
+
+func (cw *castWriter) setStyle(st gruid.Style) {
+	cw.style = st
+	cw.init = true
+	fg := map16ColorTo256(st.Fg, true)
+	bg := map16ColorTo256(st.Bg, false)
+	fmt.Fprintf(&cw.buf, "\x1b[0;38;5;%d;48;5;%d", fg, bg)
+	if st.Attrs&AttrReverse != 0 {
+		cw.buf.WriteString(";7")
+	}
+	cw.buf.WriteString("m")
+}
+
+// flush writes the buffered output as an event at a given time.
+func (cw *castWriter) flush(t time.Time) error {
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	data, err := json.Marshal([]interface{}{t.Sub(cw.start).Seconds(), "o", cw.buf.String()})
+	if err != nil {
+		return err
+	}
+	cw.buf.Reset()
+	_, err = fmt.Fprintf(cw.w, "%s\n", data)
+	return err
+}
@@ -406,7 +406,7 @@ func (c cell) Desc(g *game, p gruid.Point) (desc string) {
 			desc = "A chasm. If you jump into it, you'll reach the next level, but you'll be seriously injured."
 		}
 	case WaterCell:
-		desc = "This is shallow water. Only monsters that can swim will follow you there."
+		desc = "This is shallow water. Only monsters that can swim will follow you there, but swimming makes some noise."
 	case RubbleCell:
 		desc = "Rubblestone is a collection of rocks broken into smaller stones. They are never well illuminated."
 	case CavernCell:
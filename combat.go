@@ -25,14 +25,20 @@ func (m *monster) InflictDamage(g *game, damage, max int) {
 	g.DamagePlayer(damage)
 	g.md.WoundedAnimation()
 	if oldHP > max && g.Player.HP <= max {
-		g.StoryPrintf("Critical hit by %s (HP: %d)", m.Kind, g.Player.HP)
+		g.StoryEntry(timelineEntry{Kind: TimelineNearDeath, Name: m.Kind.String(), HP: g.Player.HP,
+			Text: fmt.Sprintf("Critical hit by %s (HP: %d)", m.Kind, g.Player.HP)})
 		g.md.WoundedAnimation() // twice
-		g.md.criticalHPWarning()
+		if g.md != nil {
+			g.md.criticalHPWarning()
+		}
 	} else if g.Player.HP > 0 {
 		g.StoryPrintf("Hit by %s (HP: %d)", m.Kind, g.Player.HP)
 	} else {
 		g.StoryPrintf("Killed by %s", m.Kind)
 	}
+	if g.Player.HP <= 0 {
+		g.Stats.KilledBy = m.Kind.String()
+	}
 	if g.Player.HP > 0 && g.Player.Inventory.Body == CloakConversion && g.Player.MP < g.Player.MPMax() {
 		g.Player.MP++
 	}
@@ -60,6 +66,9 @@ func (g *game) MakeMonstersAware() {
 }
 
 func (g *game) MakeNoise(noise int, at gruid.Point) {
+	if at == g.Player.P {
+		addHeat(&g.Stats.DNoise, g.Depth, at)
+	}
 	dij := &noisePath{g: g}
 	g.PR.BreadthFirstMap(dij, []gruid.Point{at}, noise)
 	//if at.Distance(g.Player.Pos)-noise < DefaultLOSRange && noise > 4 {
@@ -190,9 +199,7 @@ func (g *game) Jump(mons *monster) (bool, error) {
 	g.Stats.Jumps++
 	g.Printf("You jump over %s", mons.Kind.Definite(false))
 	g.StoryPrintf("Jumped over %s", mons.Kind)
-	if g.Stats.Jumps+g.Stats.WallJumps == 15 {
-		AchAcrobat.Get(g)
-	}
+	g.CheckAchievements(CounterJumps)
 	return false, nil
 }
 
@@ -244,9 +251,7 @@ func (g *game) WallJump(p gruid.Point) error {
 	g.PlacePlayerAt(q)
 	g.Stats.WallJumps++
 	g.Print("You jump by propelling yourself against the wall.")
-	if g.Stats.Jumps+g.Stats.WallJumps == 15 {
-		AchAcrobat.Get(g)
-	}
+	g.CheckAchievements(CounterJumps)
 	return nil
 }
 
@@ -264,6 +269,7 @@ const (
 
 func (g *game) HandleKill(mons *monster) {
 	g.Stats.Killed++
+	ConductPacifist.Break(g)
 	g.Stats.KilledMons[mons.Kind]++
 	if g.Player.Sees(mons.P) {
 		AchAssassin.Get(g)
@@ -271,7 +277,7 @@ func (g *game) HandleKill(mons *monster) {
 	if terrain(g.Dungeon.Cell(mons.P)) == DoorCell {
 		g.ComputeLOS()
 	}
-	g.StoryPrintf("Death of %s", mons.Kind.Indefinite(false))
+	g.StoryEntry(timelineEntry{Kind: TimelineKill, Name: mons.Kind.String(), Text: fmt.Sprintf("Death of %s", mons.Kind.Indefinite(false))})
 }
 
 const (
@@ -285,6 +291,7 @@ const (
 	SingingNoise           = 12
 	EarthquakeNoise        = 35
 	QueenRockFootstepNoise = 7
+	SwimNoise              = 4
 	DelayedHarmonicNoise   = 25
 	OricExplosionNoise     = 20
 )
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// conduct is a self-imposed challenge: a conduct stays intact as long as the
+// player never does a given thing during the game.
+type conduct string
+
+// Conducts.
+const (
+	ConductPacifist  conduct = "Pacifist"
+	ConductNoMagaras conduct = "Magaraless"
+	ConductNoRest    conduct = "Restless"
+	ConductUnseen    conduct = "Unseen"
+)
+
+// conducts lists all the conducts.
+var conducts = []conduct{
+	ConductPacifist,
+	ConductNoMagaras,
+	ConductNoRest,
+	ConductUnseen,
+}
+
+// Desc returns the rule of the conduct.
+func (c conduct) Desc() string {
+	switch c {
+	case ConductPacifist:
+		return "never kill a monster"
+	case ConductNoMagaras:
+		return "never evoke a magara"
+	case ConductNoRest:
+		return "never rest in a barrel"
+	case ConductUnseen:
+		return "never get spotted by a monster"
+	}
+	return ""
+}
+
+// Achievement returns the achievement for escaping with the conduct intact.
+func (c conduct) Achievement() achievement {
+	switch c {
+	case ConductPacifist:
+		return AchPacifist
+	case ConductNoMagaras:
+		return AchMagaraless
+	case ConductNoRest:
+		return AchRestless
+	case ConductUnseen:
+		return AchUnseen
+	}
+	return NoAchievement
+}
+
+// conductBreak records when and where a conduct was broken.
+type conductBreak struct {
+	Turn  int
+	Depth int
+}
+
+// Intact reports whether the conduct has not been broken yet.
+func (c conduct) Intact(g *game) bool {
+	_, ok := g.Stats.BrokenConducts[c]
+	return !ok
+}
+
+// Break records that the conduct was broken, if it was still intact.
+func (c conduct) Break(g *game) {
+	if !c.Intact(g) {
+		return
+	}
+	if g.Stats.BrokenConducts == nil {
+		g.Stats.BrokenConducts = map[conduct]conductBreak{}
+	}
+	g.Stats.BrokenConducts[c] = conductBreak{Turn: g.Turn, Depth: g.Depth}
+	g.StoryPrintf("Broke %s conduct", c)
+}
+
+// IntactConducts returns the conducts that have not been broken.
+func (g *game) IntactConducts() []conduct {
+	cs := []conduct{}
+	for _, c := range conducts {
+		if c.Intact(g) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// AchieveConducts awards the achievements of the conducts kept intact until
+// the escape.
+func (g *game) AchieveConducts() {
+	for _, c := range g.IntactConducts() {
+		c.Achievement().Get(g)
+	}
+}
+
+// conductStatus returns the status of a conduct as text.
+func (g *game) conductStatus(c conduct) string {
+	if c.Intact(g) {
+		return "intact"
+	}
+	b := g.Stats.BrokenConducts[c]
+	if b.Turn == 0 {
+		// break migrated from an older save: the turn is not known
+		return fmt.Sprintf("broken on depth %d", b.Depth)
+	}
+	return fmt.Sprintf("broken on depth %d, turn %d", b.Depth, b.Turn)
+}
+
+// DumpConducts writes the status of the conducts.
+func (g *game) DumpConducts(w io.Writer) {
+	fmt.Fprintf(w, "Conducts:\n")
+	for _, c := range conducts {
+		fmt.Fprintf(w, "- %-10s (%s): %s\n", c, c.Desc(), g.conductStatus(c))
+	}
+}
+
+func (md *model) openConducts() {
+	g := md.g
+	md.pagerMode = modeConducts
+	md.mode = modePager
+	md.pager.SetBox(&ui.Box{Title: ui.Text(" Conducts ").WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	lines := []ui.StyledText{}
+	for _, c := range conducts {
+		st := gruid.Style{}
+		if c.Intact(g) {
+			st = st.WithFg(ColorGreen)
+		}
+		lines = append(lines, ui.Textf(" %-10s %-31s %s", c, c.Desc(), g.conductStatus(c)).WithStyle(st))
+	}
+	md.pager.SetLines(lines)
+	md.pager.SetCursor(gruid.Point{0, 0})
+}
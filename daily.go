@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// dailyDate returns the daily challenge date corresponding to a given time.
+// Dates are in UTC, so that everyone plays the same challenge at the same
+// time.
+func dailyDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// dailySeed returns the seed of the daily challenge for a given date.
+func dailySeed(date string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("harmonist daily challenge " + date))
+	seed := int64(h.Sum64() >> 1)
+	if seed == 0 {
+		// zero means a time-based seed
+		seed = 1
+	}
+	return seed
+}
+
+// dailyEntry records the outcome of a daily challenge attempt.
+type dailyEntry struct {
+	Date         string
+	Version      string
+	Finished     bool
+	Died         bool
+	Wizard       bool
+	Depth        int
+	Turns        int
+	Shaedra      bool
+	Artifact     bool
+	Achievements []string
+}
+
+func (e dailyEntry) Outcome() string {
+	switch {
+	case !e.Finished:
+		return "abandoned"
+	case e.Died:
+		return "died"
+	default:
+		return "escaped"
+	}
+}
+
+// dailyLedger contains the results of daily challenges played with a given
+// data directory.
+type dailyLedger struct {
+	Entries []dailyEntry
+}
+
+// Entry returns the index of the entry for a given date, or -1 if there is
+// none.
+func (l *dailyLedger) Entry(date string) int {
+	for i, e := range l.Entries {
+		if e.Date == date {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *game) DailyEntry() dailyEntry {
+	e := dailyEntry{
+		Date:     g.Daily,
+		Version:  Version,
+		Finished: g.Player.HP <= 0 || g.Depth == -1,
+		Died:     g.Player.HP <= 0,
+		Wizard:   g.Wizard,
+		Depth:    max(g.Depth, g.ExploredLevels),
+		Turns:    g.Turn,
+		Shaedra:  g.LiberatedShaedra,
+		Artifact: g.LiberatedArtifact,
+	}
+	for ach := range g.Stats.Achievements {
+		e.Achievements = append(e.Achievements, string(ach))
+	}
+	sort.Strings(e.Achievements)
+	return e
+}
+
+// RecordDaily updates the daily challenge ledger with the current state of a
+// daily challenge game.
+func (g *game) RecordDaily() error {
+	if g.Daily == "" {
+		return nil
+	}
+	l, err := LoadDailyLedger()
+	if err != nil {
+		return err
+	}
+	e := g.DailyEntry()
+	if i := l.Entry(g.Daily); i >= 0 {
+		l.Entries[i] = e
+	} else {
+		l.Entries = append(l.Entries, e)
+	}
+	return SaveDailyLedger(l)
+}
+
+// startDaily replaces the new game with today's daily challenge, unless it
+// has already been attempted.
+func (md *model) startDaily() error {
+	date := dailyDate(md.now())
+	l, err := LoadDailyLedger()
+	if err != nil {
+		return fmt.Errorf("loading daily challenge results: %v", err)
+	}
+	if l.Entry(date) >= 0 {
+		return errors.New("You already attempted today's daily challenge.")
+	}
+	g := &game{Seed: dailySeed(date), Daily: date, md: md}
+	md.g = g
+	g.InitLevel()
+	g.StoryPrintf("Started daily challenge %s", date)
+	g.PrintfStyled("Daily challenge %s: you have only one attempt!", logSpecial, date)
+	g.ComputeNoise()
+	md.updateStatusInfo()
+	err = g.RecordDaily()
+	if err != nil {
+		g.PrintfStyled("Error recording daily challenge: %v", logError, err)
+	}
+	return nil
+}
+
+func (md *model) openDailyLedger() {
+	l, err := LoadDailyLedger()
+	if err != nil {
+		md.g.PrintfStyled("Error loading daily challenge results: %v", logError, err)
+		return
+	}
+	md.pagerMode = modeDailyLedger
+	md.mode = modePager
+	md.pager.SetBox(&ui.Box{Title: ui.Text(" Daily Challenges ").WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	lines := []ui.StyledText{}
+	if len(l.Entries) == 0 {
+		lines = append(lines, ui.Text(" No daily challenge attempted yet."))
+	}
+	for i := len(l.Entries) - 1; i >= 0; i-- {
+		e := l.Entries[i]
+		stt := ui.Textf(" %s %-9s depth %2d, %5d turns", e.Date, e.Outcome(), e.Depth, e.Turns)
+		if e.Shaedra {
+			stt = stt.WithText(stt.Text() + ", Shaedra")
+		}
+		if e.Artifact {
+			stt = stt.WithText(stt.Text() + ", Artifact")
+		}
+		if e.Wizard {
+			stt = stt.WithText(stt.Text() + " (wizard)")
+		}
+		stt = stt.WithStyle(gruid.Style{}.WithFg(ColorCyan))
+		lines = append(lines, stt)
+		for _, ach := range e.Achievements {
+			lines = append(lines, ui.Textf("   - %s", ach))
+		}
+	}
+	md.pager.SetLines(lines)
+	md.pager.SetCursor(gruid.Point{0, 0})
+}
@@ -33,7 +33,11 @@ func (md *model) Draw() gruid.Grid {
 		md.gd.Copy(md.pager.Draw())
 		return md.gd
 	case modeWelcome:
-		return drawWelcome(md.gd)
+		drawWelcome(md.gd)
+		if md.newGame {
+			md.drawDailyEntry(md.gd)
+		}
+		return md.gd
 	}
 	// Draw map in all other cases, as it may be covered only partially by
 	// other modes.
@@ -76,6 +80,8 @@ func (md *model) Draw() gruid.Grid {
 			}
 		case modeGameMenu, modeSettings, modeWizard:
 			md.gd.Copy(md.menu.Draw())
+		case modeMorgue:
+			md.gd.Copy(md.keysMenu.Draw())
 		case modeKeys, modeKeysChange:
 			gd := md.keysMenu.Draw()
 			max := gd.Size()
@@ -128,6 +134,12 @@ func drawWelcome(gd gruid.Grid) gruid.Grid {
 	return gd
 }
 
+func (md *model) drawDailyEntry(gd gruid.Grid) {
+	stt := ui.StyledText{}.WithMarkup('t', gruid.Style{}.WithFg(ColorYellow))
+	text := "Press @t(d)@N for today's daily challenge, or any other key for a new game."
+	stt.WithText(text).Draw(gd.Slice(gd.Range().Shift(3, 20, 0, 0)))
+}
+
 func (md *model) drawMap(gd gruid.Grid) {
 	it := md.g.Dungeon.Grid.Iterator()
 	for it.Next() {
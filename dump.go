@@ -52,6 +52,11 @@ func (g *game) SortedKilledMonsters() monsSlice {
 func (g *game) Dump() string {
 	buf := &strings.Builder{}
 	fmt.Fprintf(buf, " -- Harmonist version %s character file --\n\n", Version)
+	fmt.Fprintf(buf, "Game seed: %d\n", g.Seed)
+	if g.Daily != "" {
+		fmt.Fprintf(buf, "Daily challenge: %s\n", g.Daily)
+	}
+	fmt.Fprintf(buf, "\n")
 	if g.Wizard {
 		fmt.Fprintf(buf, "**WIZARD MODE**\n")
 	}
@@ -105,6 +110,7 @@ func (g *game) Dump() string {
 		s = ""
 	}
 	fmt.Fprintf(buf, "You explored %d level%s out of %d.\n", maxDepth, s, MaxDepth)
+	fmt.Fprintf(buf, "Score: %v\n", g.ScoreDetail())
 	fmt.Fprintf(buf, "\n")
 	fmt.Fprintf(buf, "Last messages:\n")
 	for i := len(g.Log) - 10; i < len(g.Log); i++ {
@@ -118,6 +124,8 @@ func (g *game) Dump() string {
 	buf.WriteString(g.DumpDungeon())
 	fmt.Fprintf(buf, "└%s┘\n", strings.Repeat("─", DungeonWidth))
 	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "Heatmaps:\n")
+	fmt.Fprint(buf, g.DumpHeatmaps())
 	if g.Stats.Killed > 0 {
 		fmt.Fprint(buf, g.DumpedKilledMonsters())
 		fmt.Fprintf(buf, "\n")
@@ -126,6 +134,8 @@ func (g *game) Dump() string {
 	fmt.Fprint(buf, g.DumpStory())
 	fmt.Fprintf(buf, "\n")
 	g.DetailedStatistics(buf)
+	fmt.Fprintf(buf, "\n")
+	g.DumpConducts(buf)
 	return buf.String()
 }
 
@@ -283,7 +293,11 @@ func (g *game) DetailedStatistics(w io.Writer) {
 }
 
 func (g *game) DumpStory() string {
-	return strings.Join(g.Stats.Story, "\n")
+	lines := []string{}
+	for _, e := range g.Stats.Timeline {
+		lines = append(lines, e.String())
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (g *game) DumpDungeon() string {
@@ -377,6 +391,7 @@ func (g *game) SimplifedDump(err error) string {
 		s = ""
 	}
 	fmt.Fprintf(buf, "You explored %d level%s out of %d.\n", maxDepth, s, MaxDepth)
+	fmt.Fprintf(buf, "Your score is %d.\n", g.Score())
 	fmt.Fprintf(buf, "\n")
 	if err != nil {
 		fmt.Fprintf(buf, "Error writing dump: %v.\n", err)
@@ -0,0 +1,196 @@
+package main
+
+// This file implements the JSON form of the character dump, meant for
+// scripts. Statistics mirror the stats structure as in JSON saves, except that
+// monster kinds, magara kinds and statuses are written as names, and
+// achievements are listed with the turn at which they were obtained. Timeline
+// entries have a Kind, such as "descent" or "near-death", that scripts can
+// filter on.
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// dumpJSON is the JSON form of the character dump.
+type dumpJSON struct {
+	Version   string
+	Seed      int64  `json:",omitempty"`
+	Daily     string `json:",omitempty"`
+	Wizard    bool
+	Score     int
+	Outcome   dumpOutcome
+	Player    dumpPlayer
+	Magaras   []dumpMagara
+	Inventory dumpInventory
+	Timeline  []timelineEntry
+	Stats     jsonObject
+}
+
+// dumpOutcome describes how the game ended, if it did.
+type dumpOutcome struct {
+	Escaped           bool
+	Died              bool
+	DeathDepth        int    `json:",omitempty"`
+	KilledBy          string `json:",omitempty"`
+	LiberatedShaedra  bool
+	LiberatedArtifact bool
+	Depth             int // current depth (-1 after escaping)
+	MaxDepth          int // deepest explored level
+	Turns             int
+}
+
+type dumpPlayer struct {
+	HP      int
+	HPMax   int
+	MP      int
+	MPMax   int
+	Bananas int
+}
+
+type dumpMagara struct {
+	Name    string
+	Charges int
+	Uses    int
+}
+
+type dumpInventory struct {
+	Body string `json:",omitempty"`
+	Neck string `json:",omitempty"`
+}
+
+type dumpAchievement struct {
+	Name string
+	Turn int
+}
+
+// DumpJSON returns the JSON form of the character dump.
+func (g *game) DumpJSON() ([]byte, error) {
+	d := dumpJSON{
+		Version: Version,
+		Seed:    g.Seed,
+		Daily:   g.Daily,
+		Wizard:  g.Wizard,
+		Score:   g.Score(),
+		Outcome: dumpOutcome{
+			Escaped:           g.Player.HP > 0 && g.Depth == -1,
+			Died:              g.Player.HP <= 0,
+			LiberatedShaedra:  g.LiberatedShaedra,
+			LiberatedArtifact: g.LiberatedArtifact,
+			Depth:             g.Depth,
+			MaxDepth:          max(g.Depth, g.ExploredLevels),
+			Turns:             g.Turn,
+		},
+		Player: dumpPlayer{
+			HP:      g.Player.HP,
+			HPMax:   g.Player.HPMax(),
+			MP:      g.Player.MP,
+			MPMax:   g.Player.MPMax(),
+			Bananas: g.Player.Bananas,
+		},
+		Magaras:  []dumpMagara{},
+		Timeline: []timelineEntry{},
+	}
+	if d.Outcome.Died {
+		d.Outcome.DeathDepth = g.Depth
+		d.Outcome.KilledBy = g.Stats.KilledBy
+	}
+	for _, mag := range g.Player.Magaras {
+		if mag.Kind != NoMagara {
+			d.Magaras = append(d.Magaras, dumpMagara{Name: mag.String(), Charges: mag.Charges, Uses: g.Stats.UsedMagaras[mag.Kind]})
+		}
+	}
+	if g.Player.Inventory.Body != NoItem {
+		d.Inventory.Body = g.Player.Inventory.Body.ShortDesc(g)
+	}
+	if g.Player.Inventory.Neck != NoItem {
+		d.Inventory.Neck = g.Player.Inventory.Neck.ShortDesc(g)
+	}
+	d.Timeline = append(d.Timeline, g.Stats.Timeline...)
+	stats, err := g.Stats.dumpJSON()
+	if err != nil {
+		return nil, err
+	}
+	d.Stats = stats
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.Buffer{}
+	if err := json.Indent(&buf, data, "", "\t"); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// dumpJSON returns the JSON form of the statistics. The timeline is
+// omitted, as it is part of the dump.
+func (st *stats) dumpJSON() (jsonObject, error) {
+	v := reflect.ValueOf(st).Elem()
+	t := v.Type()
+	o := jsonObject{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		var x interface{}
+		switch f.Name {
+		case "Timeline":
+			continue
+		case "KilledMons":
+			keys := []int{}
+			for mk := range st.KilledMons {
+				keys = append(keys, int(mk))
+			}
+			x = namedCounts(keys, func(k int) (string, int) {
+				return monsterKind(k).String(), st.KilledMons[monsterKind(k)]
+			})
+		case "UsedMagaras":
+			keys := []int{}
+			for mk := range st.UsedMagaras {
+				keys = append(keys, int(mk))
+			}
+			x = namedCounts(keys, func(k int) (string, int) {
+				return magara{Kind: magaraKind(k)}.String(), st.UsedMagaras[magaraKind(k)]
+			})
+		case "Statuses":
+			keys := []int{}
+			for k := range st.Statuses {
+				keys = append(keys, int(k))
+			}
+			x = namedCounts(keys, func(k int) (string, int) {
+				return status(k).String(), st.Statuses[status(k)]
+			})
+		case "Achievements":
+			achs := []dumpAchievement{}
+			for ach, turn := range st.Achievements {
+				achs = append(achs, dumpAchievement{Name: string(ach), Turn: turn})
+			}
+			sort.Slice(achs, func(i, j int) bool {
+				return achs[i].Turn < achs[j].Turn || achs[i].Turn == achs[j].Turn && achs[i].Name < achs[j].Name
+			})
+			x = achs
+		default:
+			var err error
+			x, err = toJSONValue(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+		}
+		o = append(o, jsonField{f.Name, x})
+	}
+	return o, nil
+}
+
+// namedCounts returns an object with the named counts for the given keys,
+// in key order.
+func namedCounts(keys []int, count func(k int) (string, int)) jsonObject {
+	sort.Ints(keys)
+	o := jsonObject{}
+	for _, k := range keys {
+		name, n := count(k)
+		o = append(o, jsonField{name, n})
+	}
+	return o
+}
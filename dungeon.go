@@ -10,7 +10,6 @@ import (
 	"log"
 	"math/rand"
 	"sort"
-	"time"
 
 	"github.com/anaseto/gruid"
 	"github.com/anaseto/gruid/paths"
@@ -122,6 +121,12 @@ type dgen struct {
 	PR        *paths.PathRange
 	rand      *rand.Rand
 	neighbors paths.Neighbors
+	// vault of the level, if any
+	vaultRoom  *room
+	vaultDef   vaultDef
+	vaultCells map[gruid.Point]bool
+	// dry regions of a flooded cave, computed by DryRegions
+	regions []int
 }
 
 func (dg *dgen) ConnectRoomsShortestPath(i, j int) bool {
@@ -132,9 +137,9 @@ func (dg *dgen) ConnectRoomsShortestPath(i, j int) bool {
 	r2 := dg.rooms[j]
 	var e1pos, e2pos gruid.Point
 	var e1i, e2i int
-	e1i = r1.UnusedEntry()
+	e1i = r1.UnusedEntry(dg)
 	e1pos = r1.entries[e1i].p
-	e2i = r2.UnusedEntry()
+	e2i = r2.UnusedEntry(dg)
 	e2pos = r2.entries[e2i].p
 	tp := &tunnelPath{dg: dg}
 	path := dg.PR.AstarPath(tp, e1pos, e2pos)
@@ -261,7 +266,7 @@ func (dg *dgen) PutHoledWalls(g *game, n int) {
 	it := dg.d.Grid.Iterator()
 	for it.Next() {
 		p := it.P()
-		if dg.room[p] && g.HoledWallCandidate(p) {
+		if dg.room[p] && !dg.vaultCells[p] && g.HoledWallCandidate(p) {
 			candidates = append(candidates, p)
 		}
 	}
@@ -279,7 +284,7 @@ func (dg *dgen) PutWindows(g *game, n int) {
 	it := dg.d.Grid.Iterator()
 	for it.Next() {
 		p := it.P()
-		if dg.room[p] && g.HoledWallCandidate(p) {
+		if dg.room[p] && !dg.vaultCells[p] && g.HoledWallCandidate(p) {
 			candidates = append(candidates, p)
 		}
 	}
@@ -315,7 +320,23 @@ const (
 	PlacementEdge
 )
 
-func (dg *dgen) GenRooms(templates []string, n int, pl placement) (ps []gruid.Point, ok bool) {
+// pickRoomTemplate returns a random template, according to template weights.
+func (dg *dgen) pickRoomTemplate(templates []roomTemplate) roomTemplate {
+	total := 0
+	for _, tpl := range templates {
+		total += tpl.Weight
+	}
+	n := dg.rand.Intn(total)
+	for _, tpl := range templates {
+		if n < tpl.Weight {
+			return tpl
+		}
+		n -= tpl.Weight
+	}
+	return templates[len(templates)-1]
+}
+
+func (dg *dgen) GenRooms(templates []roomTemplate, n int, pl placement) (ps []gruid.Point, ok bool) {
 	if len(templates) == 0 {
 		return nil, false
 	}
@@ -324,7 +345,7 @@ func (dg *dgen) GenRooms(templates []string, n int, pl placement) (ps []gruid.Po
 		var r *room
 		count := 500
 		var p gruid.Point
-		var tpl string
+		var tpl roomTemplate
 		for r == nil && count > 0 {
 			count--
 			switch pl {
@@ -339,8 +360,8 @@ func (dg *dgen) GenRooms(templates []string, n int, pl placement) (ps []gruid.Po
 					p = gruid.Point{3*DungeonWidth/4 + dg.rand.Intn(DungeonWidth/4) - 1, dg.rand.Intn(DungeonHeight - 1)}
 				}
 			}
-			tpl = templates[dg.rand.Intn(len(templates))]
-			r = dg.NewRoom(p, tpl)
+			tpl = dg.pickRoomTemplate(templates)
+			r = dg.NewRoom(p, tpl.Layout)
 		}
 		if r != nil {
 			switch pl {
@@ -412,8 +433,30 @@ const (
 	RandomWalkTreeCave
 	RandomSmallWalkCaveUrbanised
 	NaturalCave
+	FloodedCave
+	UnknownLayout // layout of a level from an older save
 )
 
+func (ml maplayout) String() (text string) {
+	switch ml {
+	case AutomataCave:
+		text = "automata cave"
+	case RandomWalkCave:
+		text = "random walk cave"
+	case RandomWalkTreeCave:
+		text = "random walk tree cave"
+	case RandomSmallWalkCaveUrbanised:
+		text = "urbanised cave"
+	case NaturalCave:
+		text = "natural cave"
+	case FloodedCave:
+		text = "flooded cave"
+	case UnknownLayout:
+		text = "unknown layout"
+	}
+	return text
+}
+
 func (dg *dgen) GenShaedraCell(g *game) {
 	g.Objects.Story = map[gruid.Point]story{}
 	g.Places.Shaedra = dg.spl.Shaedra
@@ -436,9 +479,11 @@ func (dg *dgen) GenArtifactPlace(g *game) {
 
 func (g *game) GenRoomTunnels(ml maplayout) {
 	dg := dgen{}
-	dg.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	dg.rand = g.rand
 	dg.PR = paths.NewPathRange(gruid.NewRange(0, 0, DungeonWidth, DungeonHeight))
 	dg.layout = ml
+	g.Layout = ml
+	g.vault = nil
 	d := &dungeon{}
 	d.Grid = rl.NewGrid(DungeonWidth, DungeonHeight)
 	dg.d = d
@@ -460,6 +505,8 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 		} else {
 			dg.GenCaveMap(21 * 47)
 		}
+	case FloodedCave:
+		dg.GenFloodedCaveMap()
 	}
 	var places []gruid.Point
 	var nspecial = 4
@@ -473,7 +520,7 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 		var ok bool
 		count := 0
 		for {
-			places, ok = dg.GenRooms(sr.Templates(), 1, pl)
+			places, ok = dg.GenRooms(sr.Templates(g), 1, pl)
 			count++
 			if count > 150 {
 				if g.Depth == WinDepth || g.Depth == MaxDepth {
@@ -486,6 +533,9 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 			}
 		}
 	}
+	if dg.GenVault(g) {
+		nspecial--
+	}
 	if g.Depth == WinDepth {
 		dg.GenShaedraCell(g)
 		nspecial--
@@ -493,6 +543,8 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 		dg.GenArtifactPlace(g)
 		nspecial--
 	}
+	roomBigTemplates := g.roomTemplatesFor(roomBig, noSpecialRoom, g.Depth)
+	roomNormalTemplates := g.roomTemplatesFor(roomNormal, noSpecialRoom, g.Depth)
 	normal := 5
 	if g.Depth < 3 {
 		nspecial--
@@ -502,6 +554,12 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 	case RandomWalkCave:
 		dg.GenRooms(roomBigTemplates, nspecial-1, PlacementRandom)
 		dg.GenRooms(roomNormalTemplates, normal, PlacementRandom)
+	case FloodedCave:
+		if g.Depth == WinDepth {
+			nspecial++
+		}
+		dg.GenRooms(roomBigTemplates, nspecial, PlacementRandom)
+		dg.GenRooms(roomNormalTemplates, normal, PlacementRandom)
 	case RandomWalkTreeCave:
 		dg.GenRooms(roomBigTemplates, nspecial+1, PlacementRandom)
 		dg.GenRooms(roomNormalTemplates, normal+2, PlacementRandom)
@@ -521,11 +579,15 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 		dg.GenRooms(roomNormalTemplates, normal+2, PlacementRandom)
 	}
 	dg.ConnectRooms()
+	dg.ConnectVault()
 	g.Dungeon = d
 	dg.PutDoors(g)
+	dg.SealVault(g)
 	dg.PlayerStartCell(g, places)
 	dg.ClearUnconnected(g)
-	if dg.rand.Intn(10) > 0 {
+	if ml == FloodedCave {
+		dg.GenChannels()
+	} else if dg.rand.Intn(10) > 0 {
 		var c cell
 		if dg.rand.Intn(5) > 1 {
 			c = ChasmCell
@@ -548,6 +610,7 @@ func (g *game) GenRoomTunnels(ml maplayout) {
 	for i := 0; i < 4+dg.rand.Intn(2); i++ {
 		dg.GenBarrel(g)
 	}
+	dg.GenVaultContents(g)
 	dg.AddSpecial(g, ml)
 	dg.PR.CCMapAll(newPather(func(p gruid.Point) bool {
 		return valid(p) && g.Dungeon.Cell(p).IsPassable()
@@ -698,7 +761,7 @@ func (dg *dgen) PutLore(g *game) {
 		if count > 2000 {
 			panic("PutLore1")
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceItem)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceItem)
 	}
 	count = 0
 	for {
@@ -741,7 +804,7 @@ func (dg *dgen) GenLight(g *game) {
 		g.Dungeon.SetCell(p, LightCell)
 	}
 	for i := 0; i < ni; i++ {
-		p := dg.rooms[RandInt(len(dg.rooms))].RandomPlaces(PlaceSpecialOrStatic)
+		p := dg.rooms[g.randInt(len(dg.rooms))].RandomPlaces(dg, PlaceSpecialOrStatic)
 		if p != invalidPos {
 			g.Dungeon.SetCell(p, LightCell)
 		} else if dg.rand.Intn(10) > 0 {
@@ -770,7 +833,7 @@ loop:
 			}
 		}
 	}
-	g.Player.P = r.RandomPlace(PlacePatrol)
+	g.Player.P = r.RandomPlace(dg, PlacePatrol)
 	switch g.Depth {
 	case 1, 4:
 	default:
@@ -779,7 +842,7 @@ loop:
 	itpos := invalidPos
 	neighbors := g.playerPassableNeighbors(g.Player.P)
 	for i := 0; i < len(neighbors); i++ {
-		j := RandInt(len(neighbors) - i)
+		j := g.randInt(len(neighbors) - i)
 		neighbors[i], neighbors[j] = neighbors[j], neighbors[i]
 	}
 loopnb:
@@ -796,10 +859,10 @@ loopnb:
 		}
 	}
 	if itpos == invalidPos {
-		itpos = r.RandomPlace(PlaceItem)
+		itpos = r.RandomPlace(dg, PlaceItem)
 	}
 	if itpos == invalidPos {
-		itpos = r.RandomPlaces(PlaceSpecialOrStatic)
+		itpos = r.RandomPlaces(dg, PlaceSpecialOrStatic)
 		if itpos == invalidPos {
 			panic("no item")
 		}
@@ -840,7 +903,7 @@ func (dg *dgen) GenPotion(g *game, ptn potion) {
 		if count > maxIterations {
 			return
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceItem)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceItem)
 	}
 	dg.d.SetCell(p, PotionCell)
 	g.Objects.Potions[p] = ptn
@@ -996,7 +1059,7 @@ func (dg *dgen) GenItem(g *game) {
 		if count > maxIterations {
 			panic("GenItem")
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceItem)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceItem)
 	}
 	g.Dungeon.SetCell(p, ItemCell)
 	var it item
@@ -1019,7 +1082,7 @@ func (dg *dgen) GenBarrierStone(g *game) {
 		if count > maxIterations {
 			panic("GenBarrierStone")
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlaces(PlaceSpecialOrStatic)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlaces(dg, PlaceSpecialOrStatic)
 	}
 	g.Dungeon.SetCell(p, StoneCell)
 	g.Objects.Stones[p] = SealStone
@@ -1033,7 +1096,7 @@ func (dg *dgen) GenMagara(g *game) {
 		if count > maxIterations {
 			panic("GenMagara")
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceItem)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceItem)
 	}
 	g.Dungeon.SetCell(p, MagaraCell)
 	mag := g.RandomMagara()
@@ -1100,7 +1163,7 @@ func (dg *dgen) GenBarrel(g *game) {
 		if count > 500 {
 			return
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceSpecialStatic)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceSpecialStatic)
 	}
 	g.Dungeon.SetCell(p, BarrelCell)
 	g.Objects.Barrels[p] = true
@@ -1114,7 +1177,7 @@ func (dg *dgen) GenTable(g *game) {
 		if count > 500 {
 			return
 		}
-		p = dg.rooms[RandInt(len(dg.rooms))].RandomPlaces(PlaceSpecialOrStatic)
+		p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlaces(dg, PlaceSpecialOrStatic)
 	}
 	g.Dungeon.SetCell(p, TableCell)
 }
@@ -1159,7 +1222,7 @@ func (dg *dgen) RandomInStone(g *game) stone {
 		// fog stone less often inside
 		instones = append(instones, FogStone)
 	}
-	return instones[RandInt(len(instones))]
+	return instones[g.randInt(len(instones))]
 }
 
 func (dg *dgen) RandomOutStone(g *game) stone {
@@ -1206,7 +1269,7 @@ func (dg *dgen) GenStones(g *game) {
 					p = dg.CaveGroundCell(g)
 					break
 				}
-				p = dg.rooms[RandInt(len(dg.rooms))].RandomPlace(PlaceStatic)
+				p = dg.rooms[g.randInt(len(dg.rooms))].RandomPlace(dg, PlaceStatic)
 			}
 			st = dg.RandomInStone(g)
 		} else {
@@ -1253,7 +1316,7 @@ func (dg *dgen) GenLake(c cell) {
 		}
 	}
 	count := 0
-	var bestpos = walls[RandInt(len(walls))]
+	var bestpos = walls[dg.rand.Intn(len(walls))]
 	var bestsize int
 	d := dg.d
 	passable := func(p gruid.Point) func(q gruid.Point) bool {
@@ -1262,7 +1325,7 @@ func (dg *dgen) GenLake(c cell) {
 		}
 	}
 	for {
-		p := walls[RandInt(len(walls))]
+		p := walls[dg.rand.Intn(len(walls))]
 		sp := newPather(passable(p))
 		size := len(dg.PR.CCMap(sp, p))
 		count++
@@ -1280,6 +1343,88 @@ func (dg *dgen) GenLake(c cell) {
 	}
 }
 
+// GenChannels floods the cave passages, leaving ground banks along walls and
+// rooms. Tunnels between rooms are kept, and cross the channels. Foliage from
+// room templates is cleared, too.
+func (dg *dgen) GenChannels() {
+	d := dg.d
+	water := []gruid.Point{}
+	it := d.Grid.Iterator()
+	for it.Next() {
+		p := it.P()
+		if terrain(cell(it.Cell())) == FoliageCell {
+			it.SetCell(rl.Cell(GroundCell))
+			continue
+		}
+		if terrain(cell(it.Cell())) != GroundCell || dg.room[p] || dg.tunnel[p] {
+			continue
+		}
+		bank := false
+		for _, q := range dg.neighbors.All(p, valid) {
+			if terrain(d.Cell(q)) == WallCell || dg.room[q] {
+				bank = true
+				break
+			}
+		}
+		if !bank {
+			water = append(water, p)
+		}
+	}
+	for _, p := range water {
+		d.SetCell(p, WaterCell)
+	}
+}
+
+// DryRegions returns, for each cell, a label of the region of passable cells
+// that can be reached from it without swimming nor using a crossing, that
+// is a tunnel cell next to a channel. Impassable cells, water and crossings
+// have a zero label. Regions are computed once, after GenChannels.
+func (dg *dgen) DryRegions() []int {
+	if dg.regions != nil {
+		return dg.regions
+	}
+	d := dg.d
+	dry := func(p gruid.Point) bool {
+		if !valid(p) {
+			return false
+		}
+		c := d.Cell(p)
+		if !c.IsPassable() || terrain(c) == WaterCell {
+			return false
+		}
+		if dg.tunnel[p] {
+			for _, q := range dg.neighbors.Cardinal(p, valid) {
+				if terrain(d.Cell(q)) == WaterCell {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	dg.regions = make([]int, DungeonNCells)
+	n := 0
+	for i := range dg.regions {
+		p := idxtopos(i)
+		if dg.regions[i] != 0 || !dry(p) {
+			continue
+		}
+		n++
+		dg.regions[i] = n
+		queue := []gruid.Point{p}
+		for len(queue) > 0 {
+			q := queue[0]
+			queue = queue[1:]
+			for _, r := range dg.neighbors.Cardinal(q, dry) {
+				if dg.regions[idx(r)] == 0 {
+					dg.regions[idx(r)] = n
+					queue = append(queue, r)
+				}
+			}
+		}
+	}
+	return dg.regions
+}
+
 func (dg *dgen) GenQueenRock() {
 	cavern := []gruid.Point{}
 	for i := 0; i < DungeonNCells; i++ {
@@ -1293,7 +1438,7 @@ func (dg *dgen) GenQueenRock() {
 		return
 	}
 	for i := 0; i < 1+dg.rand.Intn(2); i++ {
-		p := cavern[RandInt(len(cavern))]
+		p := cavern[dg.rand.Intn(len(cavern))]
 		passable := func(q gruid.Point) bool {
 			return valid(q) && terrain(dg.d.Cell(q)) == CavernCell && distance(q, p) < 15+dg.rand.Intn(5)
 		}
@@ -1356,6 +1501,17 @@ func (dg *dgen) GenCaveMap(size int) {
 	dg.Foliage(false)
 }
 
+// GenFloodedCaveMap generates a wide cave without foliage, to be flooded by
+// GenChannels.
+func (dg *dgen) GenFloodedCaveMap() {
+	mg := rl.MapGen{
+		Rand: dg.rand,
+		Grid: dg.d.Grid,
+	}
+	wlk := walker{rand: dg.rand}
+	mg.RandomWalkCave(wlk, rl.Cell(GroundCell), float64(21*50)/float64(DungeonNCells), 8)
+}
+
 func (dg *dgen) DigBlock(block []gruid.Point) []gruid.Point {
 	d := dg.d
 	p := dg.WallCell()
@@ -1374,7 +1530,7 @@ func (dg *dgen) DigBlock(block []gruid.Point) []gruid.Point {
 		if d.HasFreeNeighbor(&dg.neighbors, p) {
 			break
 		}
-		p = randomNeighbor(p)
+		p = walker{rand: dg.rand}.Neighbor(p)
 		if !valid(p) {
 			block = block[:0]
 			p = dg.WallCell()
@@ -1446,10 +1602,10 @@ loop:
 			break
 		}
 		for i := 0; i < 20; i++ {
-			r := dg.rooms[RandInt(len(dg.rooms)-1)]
+			r := dg.rooms[g.randInt(len(dg.rooms)-1)]
 			for _, e := range r.places {
 				if e.kind == PlaceSpecialStatic {
-					p = r.RandomPlace(pl)
+					p = r.RandomPlace(dg, pl)
 					break
 				}
 			}
@@ -1457,8 +1613,8 @@ loop:
 				break loop
 			}
 		}
-		r := dg.rooms[RandInt(len(dg.rooms)-1)]
-		p = r.RandomPlace(pl)
+		r := dg.rooms[g.randInt(len(dg.rooms)-1)]
+		p = r.RandomPlace(dg, pl)
 	}
 	bandinfo.Path = append(bandinfo.Path, p)
 	bandinfo.Beh = BehGuard
@@ -1476,7 +1632,7 @@ func (dg *dgen) BandInfoGuardSpecial(g *game, band monsterBand) bandInfo {
 			p = dg.InsideCell(g)
 			break
 		}
-		p = r.RandomPlace(PlacePatrolSpecial)
+		p = r.RandomPlace(dg, PlacePatrolSpecial)
 		if p != invalidPos && !g.MonsterAt(p).Exists() {
 			break
 		}
@@ -1496,9 +1652,20 @@ func (dg *dgen) BandInfoPatrol(g *game, band monsterBand, pl placeKind) bandInfo
 			p = dg.InsideCell(g)
 			break
 		}
-		p = dg.rooms[RandInt(len(dg.rooms)-1)].RandomPlace(pl)
+		p = dg.rooms[g.randInt(len(dg.rooms)-1)].RandomPlace(dg, pl)
 	}
 	target := invalidPos
+	if dg.layout == FloodedCave {
+		// patrol across the channels, when possible
+		regions := dg.DryRegions()
+		for i := 0; i < 100; i++ {
+			q := dg.rooms[g.randInt(len(dg.rooms)-1)].RandomPlace(dg, pl)
+			if q != invalidPos && regions[idx(q)] != regions[idx(p)] {
+				target = q
+				break
+			}
+		}
+	}
 	count = 0
 	for target == invalidPos {
 		// TODO: only find place in other room?
@@ -1507,7 +1674,7 @@ func (dg *dgen) BandInfoPatrol(g *game, band monsterBand, pl placeKind) bandInfo
 			target = dg.InsideCell(g)
 			break
 		}
-		target = dg.rooms[RandInt(len(dg.rooms)-1)].RandomPlace(pl)
+		target = dg.rooms[g.randInt(len(dg.rooms)-1)].RandomPlace(dg, pl)
 	}
 	bandinfo.Path = append(bandinfo.Path, p)
 	bandinfo.Path = append(bandinfo.Path, target)
@@ -1526,7 +1693,7 @@ func (dg *dgen) BandInfoPatrolSpecial(g *game, band monsterBand) bandInfo {
 			p = dg.InsideCell(g)
 			break
 		}
-		p = r.RandomPlace(PlacePatrolSpecial)
+		p = r.RandomPlace(dg, PlacePatrolSpecial)
 		if p != invalidPos && !g.MonsterAt(p).Exists() {
 			break
 		}
@@ -1540,7 +1707,7 @@ func (dg *dgen) BandInfoPatrolSpecial(g *game, band monsterBand) bandInfo {
 			p = dg.InsideCell(g)
 			break
 		}
-		target = r.RandomPlace(PlacePatrolSpecial)
+		target = r.RandomPlace(dg, PlacePatrolSpecial)
 		if target != invalidPos {
 			break
 		}
@@ -1619,8 +1786,8 @@ func (g *game) FreeCellForMonster() gruid.Point {
 		if count > maxIterations {
 			panic("FreeCellForMonster")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.randInt(DungeonWidth)
+		y := g.randInt(DungeonHeight)
 		p := gruid.Point{x, y}
 		c := d.Cell(p)
 		if !c.IsPassable() {
@@ -1651,7 +1818,7 @@ func (g *game) FreeCellForBandMonster(p gruid.Point) gruid.Point {
 			count = maxIterations + 1
 			continue
 		}
-		r := RandInt(len(neighbors))
+		r := g.randInt(len(neighbors))
 		p = neighbors[r]
 		if g.Player != nil && distance(g.Player.P, p) < 8 {
 			continue
@@ -1704,6 +1871,13 @@ func (dg *dgen) PutMonsterBand(g *game, band monsterBand) bool {
 	default:
 		bdinf = dg.BandInfoPatrol(g, band, PlacePatrol)
 	}
+	dg.placeBand(g, monsters, bdinf, awake)
+	return true
+}
+
+// placeBand places the monsters of a band, starting from the first position
+// of the band's path.
+func (dg *dgen) placeBand(g *game, monsters []monsterKind, bdinf bandInfo, awake bool) {
 	g.Bands = append(g.Bands, bdinf)
 	var p gruid.Point
 	if len(bdinf.Path) == 0 {
@@ -1722,7 +1896,7 @@ func (dg *dgen) PutMonsterBand(g *game, band monsterBand) bool {
 			mons.State = Wandering
 		}
 		g.Monsters = append(g.Monsters, mons)
-		mons.Init()
+		mons.Init(g)
 		mons.Index = len(g.Monsters) - 1
 		mons.Band = len(g.Bands) - 1
 		mons.PlaceAtStart(g, p)
@@ -1731,16 +1905,15 @@ func (dg *dgen) PutMonsterBand(g *game, band monsterBand) bool {
 			p = g.FreeCellForBandMonster(p)
 		}
 	}
-	return true
 }
 
 func (dg *dgen) PutRandomBand(g *game, bands []monsterBand) bool {
-	return dg.PutMonsterBand(g, bands[RandInt(len(bands))])
+	return dg.PutMonsterBand(g, bands[g.randInt(len(bands))])
 }
 
 func (dg *dgen) PutRandomBandN(g *game, bands []monsterBand, n int) {
 	for i := 0; i < n; i++ {
-		dg.PutMonsterBand(g, bands[RandInt(len(bands))])
+		dg.PutMonsterBand(g, bands[g.randInt(len(bands))])
 	}
 }
 
@@ -1777,6 +1950,13 @@ func (dg *dgen) GenMonsters(g *game) {
 	bandVampirePair := []monsterBand{PairVampire}
 	bandOricCelmistPair := []monsterBand{PairOricCelmist}
 	bandHarmonicCelmistPair := []monsterBand{PairHarmonicCelmist}
+	if dg.layout == FloodedCave {
+		// swimmers replace land animals, and vampires are more common
+		bandsAnimals = []monsterBand{LoneDog, LoneBlinkingFrog}
+		bandYack = bandFrog
+		bandNadre = bandFrog
+		bandsBipeds = append([]monsterBand{LoneVampire, LoneVampire}, bandsBipeds...)
+	}
 	// special bands
 	if g.Params.Special[g.Depth] != noSpecialRoom {
 		switch dg.special {
@@ -1854,10 +2034,14 @@ func (dg *dgen) GenMonsters(g *game) {
 	if g.Depth == g.Params.CrazyImp {
 		dg.PutRandomBand(g, []monsterBand{UniqueCrazyImp})
 	}
+	dg.PutVaultBands(g)
 	dg.PutRandomBandN(g, bandsButterfly, 2)
 	if dg.layout == RandomSmallWalkCaveUrbanised {
 		dg.PutRandomBandN(g, bandsGuard, 1+(g.Depth+1)/4)
 	}
+	if g.Params.Event[g.Depth] == BlackoutLevel {
+		dg.PutRandomBandN(g, bandHazeCat, 1+g.Depth/4)
+	}
 	switch g.Depth {
 	case 1:
 		// 8-9
@@ -3,9 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/anaseto/gruid"
 	"github.com/anaseto/gruid/paths"
@@ -66,7 +64,7 @@ func (d *dungeon) String() string {
 func TestAutomataCave(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
-		g.initrand()
+		g.InitRand()
 		g.InitFirstLevel()
 		g.InitLevelStructures()
 		g.GenRoomTunnels(AutomataCave)
@@ -79,7 +77,7 @@ func TestAutomataCave(t *testing.T) {
 func TestRandomWalkCave(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
-		g.initrand()
+		g.InitRand()
 		g.InitFirstLevel()
 		g.InitLevelStructures()
 		g.GenRoomTunnels(RandomWalkCave)
@@ -92,7 +90,7 @@ func TestRandomWalkCave(t *testing.T) {
 func TestRandomWalkTreeCave(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
-		g.initrand()
+		g.InitRand()
 		g.InitFirstLevel()
 		g.InitLevelStructures()
 		g.GenRoomTunnels(RandomWalkTreeCave)
@@ -105,7 +103,7 @@ func TestRandomWalkTreeCave(t *testing.T) {
 func TestRandomSmallWalkCaveUrbanised(t *testing.T) {
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
-		g.initrand()
+		g.InitRand()
 		g.InitFirstLevel()
 		g.InitLevelStructures()
 		g.GenRoomTunnels(RandomSmallWalkCaveUrbanised)
@@ -115,21 +113,242 @@ func TestRandomSmallWalkCaveUrbanised(t *testing.T) {
 	}
 }
 
-func (g *game) initrand() {
-	if g.rand == nil {
-		g.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+func TestNaturalCave(t *testing.T) {
+	for i := 0; i < Rounds; i++ {
+		g := &game{}
+		g.InitRand()
+		g.InitFirstLevel()
+		g.InitLevelStructures()
+		g.GenRoomTunnels(NaturalCave)
+		if !g.Dungeon.connex(g.PR) {
+			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
+		}
 	}
 }
 
-func TestNaturalCave(t *testing.T) {
+func TestFloodedCave(t *testing.T) {
+	swimmers := 0
 	for i := 0; i < Rounds; i++ {
 		g := &game{}
-		g.initrand()
+		g.InitRand()
 		g.InitFirstLevel()
 		g.InitLevelStructures()
-		g.GenRoomTunnels(NaturalCave)
+		g.GenRoomTunnels(FloodedCave)
 		if !g.Dungeon.connex(g.PR) {
 			t.Errorf("Not connex:\n%s\n", g.Dungeon.String())
 		}
+		if g.Layout != FloodedCave {
+			t.Errorf("bad layout: %v", g.Layout)
+		}
+		for _, mons := range g.Monsters {
+			if mons.Kind.CanSwim() {
+				swimmers++
+			}
+		}
+		water := 0
+		it := g.Dungeon.Grid.Iterator()
+		for it.Next() {
+			switch terrain(cell(it.Cell())) {
+			case WaterCell:
+				water++
+			case FoliageCell:
+				t.Errorf("foliage in flooded cave at %v", it.P())
+			}
+		}
+		if water < 50 {
+			t.Errorf("not flooded: %d water cells", water)
+		}
+	}
+	if swimmers == 0 {
+		t.Errorf("no swimming monsters")
+	}
+}
+
+func TestVaults(t *testing.T) {
+	for i := 0; i < Rounds; i++ {
+		g := &game{}
+		g.InitRand()
+		g.InitFirstLevel()
+		g.InitLevelStructures()
+		g.Depth = 3 + i%8
+		if g.Depth == WinDepth {
+			g.Depth--
+		}
+		g.Params.Vaults[g.Depth] = true
+		g.GenRoomTunnels(AutomataCave)
+		if g.vault == nil {
+			t.Errorf("no vault at depth %d", g.Depth)
+			continue
+		}
+		p := g.vault.Reward
+		switch terrain(g.Dungeon.Cell(p)) {
+		case MagaraCell, ItemCell, PotionCell:
+		default:
+			t.Errorf("no reward in %s: %v", g.vault.Def.Room.Name, g.Dungeon.Cell(p).ShortDesc(g, p))
+		}
+		d := g.Dungeon
+		g.PR.CCMap(newPather(func(q gruid.Point) bool { return d.Cell(q).IsPlayerPassable() }), p)
+		if g.PR.CCMapAt(g.Player.P) == -1 {
+			t.Errorf("player cannot enter %s", g.vault.Def.Room.Name)
+		}
+		g.PR.CCMap(newPather(func(q gruid.Point) bool { return d.Cell(q).IsDoorPassable() }), p)
+		if g.PR.CCMapAt(g.Player.P) != -1 {
+			t.Errorf("%s entered without holed walls", g.vault.Def.Room.Name)
+		}
+	}
+}
+
+func TestGenStats(t *testing.T) {
+	const n = 2
+	gs := generateStats(n, 1)
+	for depth := 1; depth <= MaxDepth; depth++ {
+		samples := gs.Samples[genStatsKey{depth, allLayouts}]
+		if len(samples) != n {
+			t.Fatalf("depth %d: %d levels", depth, len(samples))
+		}
+		if samples[0]["monsters"] == 0 || samples[0]["dangerousness"] == 0 {
+			t.Errorf("depth %d: no monsters", depth)
+		}
+	}
+	b := &bytes.Buffer{}
+	if err := gs.WriteCSV(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("depth,layout,levels,metric,mean,min,max\n")) {
+		t.Errorf("bad CSV header")
+	}
+}
+
+func TestRoomTemplates(t *testing.T) {
+	tpl, err := ParseRoomTemplate("hall", []byte("kind: big\ndepths: 3-8\nweight: 2\n\n#+##\n#..#\n##+#\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Kind != roomBig || tpl.MinDepth != 3 || tpl.MaxDepth != 8 || tpl.Weight != 2 {
+		t.Errorf("bad metadata: %+v", tpl)
+	}
+	if tpl.Allowed(2) || !tpl.Allowed(5) || tpl.Allowed(9) {
+		t.Errorf("bad allowed depths: %+v", tpl)
+	}
+	bad := []string{
+		"kind: huge\n\n#+#\n",
+		"kind: special\n\n#+#\n",
+		"special: nixes\n\n#+#\n",
+		"depths: 5-2\n\n#+#\n",
+		"weight: -1\n\n#+#\n",
+		"colour: red\n\n#+#\n",
+		"\n#+#\n#X#\n",
+	}
+	for _, s := range bad {
+		if _, err := ParseRoomTemplate("bad", []byte(s)); err == nil {
+			t.Errorf("no error for %q", s)
+		}
+	}
+	defer func() { roomTemplates, userRooms = builtinRoomTemplates(), nil }()
+	n := len(roomTemplates)
+	off, err := ParseRoomTemplate("RoomLittle", []byte("weight: 0\n\n#+#\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddRoomTemplates([]roomTemplate{off, tpl})
+	if len(roomTemplates) != n+1 {
+		t.Errorf("bad number of templates: %d", len(roomTemplates))
+	}
+	if userRoomsDigest() == 0 {
+		t.Errorf("no digest for added templates")
+	}
+	g := &game{}
+	for _, tpl := range g.roomTemplatesFor(roomNormal, noSpecialRoom, 1) {
+		if tpl.Name == "RoomLittle" {
+			t.Errorf("disabled template used")
+		}
+	}
+	if tpls := g.roomTemplatesFor(roomBig, noSpecialRoom, 5); tpls[len(tpls)-1].Name != "hall" {
+		t.Errorf("added template not used")
+	}
+	g.Daily = "2021-01-01"
+	if tpls := g.roomTemplatesFor(roomBig, noSpecialRoom, 5); tpls[len(tpls)-1].Name == "hall" {
+		t.Errorf("added template used in daily challenge")
+	}
+}
+
+func TestSpecialRoomFallback(t *testing.T) {
+	defer func() { roomTemplates, userRooms = builtinRoomTemplates(), nil }()
+	for _, name := range []string{"CellShaedra", "CellShaedra2", "CellShaedra3", "CellShaedra4"} {
+		tpl, err := ParseRoomTemplate(name, []byte("kind: special\nspecial: Shaedra's cell\nweight: 0\n\n"+CellShaedra))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AddRoomTemplates([]roomTemplate{tpl})
+	}
+	g := &game{}
+	g.InitRand()
+	g.InitFirstLevel()
+	g.InitLevelStructures()
+	g.Depth = WinDepth
+	if len(roomShaedra.Templates(g)) == 0 {
+		t.Fatalf("no Shaedra's cell templates")
+	}
+	g.GenRoomTunnels(AutomataCave)
+	if g.Places.Shaedra == (gruid.Point{}) {
+		t.Errorf("no Shaedra's cell")
+	}
+}
+
+func TestCheckRoomTemplates(t *testing.T) {
+	good, err := ParseRoomTemplate("RoomLittle", []byte(RoomLittle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := []string{
+		"\n#####\n#P.!#\n#.>.#\n#####\n",
+		"kind: special\nspecial: Shaedra's cell\n\n###+###\n#G...G#\n###+###\n",
+	}
+	tpls := []roomTemplate{good}
+	for i, s := range bad {
+		tpl, err := ParseRoomTemplate(fmt.Sprintf("bad%d", i), []byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tpls = append(tpls, tpl)
+	}
+	ok, err := CheckRoomTemplates(tpls)
+	if err == nil {
+		t.Errorf("no error for bad templates")
+	}
+	if len(ok) != 1 || ok[0].Name != "RoomLittle" {
+		t.Errorf("bad accepted templates: %+v", ok)
+	}
+}
+
+func TestLintRooms(t *testing.T) {
+	for _, tpl := range builtinRoomTemplates() {
+		if probs := LintRoomTemplate(tpl); len(probs) > 0 {
+			t.Errorf("%s: %v", tpl.Name, probs)
+		}
+	}
+	for _, vd := range vaults {
+		if probs := LintRoomTemplate(vd.Room); len(probs) > 0 {
+			t.Errorf("%s: %v", vd.Room.Name, probs)
+		}
+	}
+	bad := map[string]string{
+		"walled":  "\n#+###\n#.#P#\n#_#!#\n#####",
+		"noplace": "\n#+##\n#..#\n####",
+		"noentry": "\n####\n#P!#\n#_.#\n####",
+		"symbol":  "\n#+##\n#PX#\n#_!#\n####",
+	}
+	for name, layout := range bad {
+		if probs := LintRoomTemplate(builtinRoom(name, layout, roomNormal, noSpecialRoom)); len(probs) == 0 {
+			t.Errorf("%s: no problems found", name)
+		}
+	}
+	tpl := builtinRoom("cell", "\n#+###\n#.G.#\n#.###\n#SMΔ#\n#####", roomSpecial, roomShaedra)
+	if probs := LintRoomTemplate(tpl); len(probs) != 0 {
+		t.Errorf("cell: %v", probs)
+	}
+	tpl.Layout = "\n#+###\n#.G.#\n#####\n#SMΔ#\n#####"
+	if probs := LintRoomTemplate(tpl); len(probs) == 0 {
+		t.Errorf("sealed cell: no problems found")
 	}
 }
@@ -3,32 +3,301 @@ package main
 import (
 	"bytes"
 	"compress/zlib"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
 
 	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/rl"
 )
 
+// eventTypes contains a value of each type of event that can be found in the
+// event queue.
+var eventTypes = []rl.Event{
+	&playerEvent{},
+	&statusEvent{},
+	&monsterTurnEvent{},
+	&monsterStatusEvent{},
+	&posEvent{},
+	endTurnEvent(0),
+}
+
 func init() {
-	gob.Register(&playerEvent{})
-	gob.Register(&statusEvent{})
-	gob.Register(&monsterTurnEvent{})
-	gob.Register(&monsterStatusEvent{})
-	gob.Register(&posEvent{})
-	gob.Register(endTurnEvent(0))
+	for _, ev := range eventTypes {
+		gob.Register(ev)
+	}
+}
+
+// saveFormat is the current save format number. It has to be increased
+// whenever a change in the game's structures requires fixing older saves, and
+// the corresponding migration appended to saveMigrations.
+const saveFormat = 4
+
+// saveHeader is the top-level structure of a save. Saves from v0.5.0 and
+// earlier were just a gob encoded game: decoding one as a saveHeader only
+// fills Version, so they are recognized by their zero Format.
+//
+// Only format 0 saves from v0.5.0 can be migrated: the game structures of
+// earlier versions are not known anymore, so such saves are refused, as they
+// were before by the version check.
+type saveHeader struct {
+	Format  int    // save format number
+	Version string // version of the game that wrote the save
+	Game    []byte // gob encoded game
+}
+
+// saveMigration upgrades a game decoded from a save in a given format to the
+// next format. The gob encoded game is provided too, so that a migration can
+// recover renamed or retyped fields by decoding it into a legacy structure.
+type saveMigration func(g *game, data []byte) error
+
+// saveMigrations[n] upgrades a game from save format n to format n+1.
+var saveMigrations = []saveMigration{
+	migrateSaveFormat0,
+	migrateSaveFormat1,
+	migrateSaveFormat2,
+	migrateSaveFormat3,
+}
+
+// saveFormat0Version is the only version whose format 0 saves are supported.
+const saveFormat0Version = "v0.5.0"
+
+// migrateSaveFormat0 upgrades a game from v0.5.0, which did not have its own
+// random source yet, nor some of the structures added since. The fields of
+// v0.5.0 games have not been renamed nor retyped since, except the timeline,
+// which is recovered by migrateSaveFormat1.
+func migrateSaveFormat0(g *game, data []byte) error {
+	g.InitRand()
+	g.initMissingStructures()
+	return nil
+}
+
+// migrateSaveFormat1 upgrades a game whose timeline was a list of strings,
+// guessing the kinds of its entries.
+func migrateSaveFormat1(g *game, data []byte) error {
+	var lg struct {
+		Stats struct {
+			Story []string
+		}
+	}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&lg)
+	if err != nil {
+		return err
+	}
+	for _, s := range lg.Stats.Story {
+		g.Stats.Timeline = append(g.Stats.Timeline, parseStoryEntry(s))
+	}
+	return nil
+}
+
+// migrateSaveFormat2 upgrades a game from before conducts were tracked,
+// breaking the conducts whose statistics show they were not kept. The turn of
+// a break is taken from the first matching timeline entry, if any: otherwise
+// only the first depth where it happened is known, and the turn is left zero.
+func migrateSaveFormat2(g *game, data []byte) error {
+	st := &g.Stats
+	brk := func(c conduct, n int, depths *[MaxDepth + 1]int, match func(e timelineEntry) bool) {
+		if n == 0 {
+			return
+		}
+		b := conductBreak{Depth: g.Depth}
+		if depths != nil {
+			for depth, m := range depths {
+				if m > 0 {
+					b.Depth = depth
+					break
+				}
+			}
+		}
+		for _, e := range st.Timeline {
+			if match != nil && match(e) {
+				b = conductBreak{Turn: e.Turn, Depth: e.Depth}
+				break
+			}
+		}
+		if st.BrokenConducts == nil {
+			st.BrokenConducts = map[conduct]conductBreak{}
+		}
+		st.BrokenConducts[c] = b
+	}
+	brk(ConductPacifist, st.Killed, nil, func(e timelineEntry) bool {
+		return e.Kind == TimelineKill
+	})
+	brk(ConductNoMagaras, st.MagarasUsed, &st.DMagaraUses, func(e timelineEntry) bool {
+		return e.Kind == TimelineMagaraUse
+	})
+	brk(ConductNoRest, st.Rest, &st.DRests, func(e timelineEntry) bool {
+		return strings.HasPrefix(e.Text, "Rested in barrel")
+	})
+	brk(ConductUnseen, st.NSpotted, &st.DSpotted, nil)
+	return nil
+}
+
+// migrateSaveFormat3 upgrades a game from before the level's layout was
+// saved. The layout of the current level cannot be recovered reliably from
+// its terrain: lakes and burnt foliage can make any cave look flooded. So it
+// is left unknown, and treated as not flooded, which only means that
+// swimming is silent until the next level.
+func migrateSaveFormat3(g *game, data []byte) error {
+	g.Layout = UnknownLayout
+	return nil
+}
+
+// initMissingStructures initializes the game's maps that were not present in
+// an older save, without clearing the others.
+func (g *game) initMissingStructures() {
+	if g.MonstersPosCache == nil {
+		g.MonstersPosCache = make([]int, DungeonNCells)
+	}
+	if g.Noise == nil {
+		g.Noise = map[gruid.Point]bool{}
+	}
+	if g.TerrainKnowledge == nil {
+		g.TerrainKnowledge = map[gruid.Point]cell{}
+	}
+	if g.ExclusionsMap == nil {
+		g.ExclusionsMap = map[gruid.Point]bool{}
+	}
+	if g.MagicalBarriers == nil {
+		g.MagicalBarriers = map[gruid.Point]cell{}
+	}
+	if g.LastMonsterKnownAt == nil {
+		g.LastMonsterKnownAt = map[gruid.Point]int{}
+	}
+	if g.NoiseIllusion == nil {
+		g.NoiseIllusion = map[gruid.Point]bool{}
+	}
+	if g.Clouds == nil {
+		g.Clouds = map[gruid.Point]cloud{}
+	}
+	if g.MonsterLOS == nil {
+		g.MonsterLOS = map[gruid.Point]bool{}
+	}
+	if g.GeneratedLore == nil {
+		g.GeneratedLore = map[int]bool{}
+	}
+	if g.RaysCache == nil {
+		g.RaysCache = rayMap{}
+	}
+	g.Objects.initMissing()
+	g.Stats.initMissing()
+}
+
+func (o *objects) initMissing() {
+	if o.Magaras == nil {
+		o.Magaras = map[gruid.Point]magara{}
+	}
+	if o.Lore == nil {
+		o.Lore = map[gruid.Point]int{}
+	}
+	if o.Items == nil {
+		o.Items = map[gruid.Point]item{}
+	}
+	if o.Scrolls == nil {
+		o.Scrolls = map[gruid.Point]scroll{}
+	}
+	if o.Stairs == nil {
+		o.Stairs = map[gruid.Point]stair{}
+	}
+	if o.Bananas == nil {
+		o.Bananas = map[gruid.Point]bool{}
+	}
+	if o.Barrels == nil {
+		o.Barrels = map[gruid.Point]bool{}
+	}
+	if o.Lights == nil {
+		o.Lights = map[gruid.Point]bool{}
+	}
+	if o.FakeStairs == nil {
+		o.FakeStairs = map[gruid.Point]bool{}
+	}
+	if o.Potions == nil {
+		o.Potions = map[gruid.Point]potion{}
+	}
+}
+
+func (st *stats) initMissing() {
+	if st.KilledMons == nil {
+		st.KilledMons = map[monsterKind]int{}
+	}
+	if st.UsedMagaras == nil {
+		st.UsedMagaras = map[magaraKind]int{}
+	}
+	if st.Achievements == nil {
+		st.Achievements = map[achievement]int{}
+	}
+	if st.Lore == nil {
+		st.Lore = map[int]bool{}
+	}
+	if st.Statuses == nil {
+		st.Statuses = map[status]int{}
+	}
+	if st.AtNotablePos == nil {
+		st.AtNotablePos = map[gruid.Point]bool{}
+	}
 }
 
 func (g *game) GameSave() ([]byte, error) {
-	data := bytes.Buffer{}
-	enc := gob.NewEncoder(&data)
+	gdata := bytes.Buffer{}
+	enc := gob.NewEncoder(&gdata)
 	err := enc.Encode(g)
 	if err != nil {
 		return nil, err
 	}
+	data := bytes.Buffer{}
+	enc = gob.NewEncoder(&data)
+	err = enc.Encode(&saveHeader{Format: saveFormat, Version: Version, Game: gdata.Bytes()})
+	if err != nil {
+		return nil, err
+	}
 	var buf bytes.Buffer
 	w := zlib.NewWriter(&buf)
 	w.Write(data.Bytes())
 	w.Close()
-	return buf.Bytes(), nil
+	return wrapSave(buf.Bytes()), nil
+}
+
+// saveMagic starts checksummed saves. It is followed by the length and the
+// CRC-32 checksum of the compressed save, both as big-endian uint32. Older
+// saves start directly with the compressed data.
+const saveMagic = "HRMNSAVE"
+
+const saveMagicLen = len(saveMagic) + 8
+
+var errSaveCorrupted = errors.New("save file is corrupted (checksum mismatch)")
+
+func wrapSave(payload []byte) []byte {
+	data := make([]byte, saveMagicLen, saveMagicLen+len(payload))
+	copy(data, saveMagic)
+	binary.BigEndian.PutUint32(data[len(saveMagic):], uint32(len(payload)))
+	binary.BigEndian.PutUint32(data[len(saveMagic)+4:], crc32.ChecksumIEEE(payload))
+	return append(data, payload...)
+}
+
+// unwrapSave verifies a save's length and checksum, and returns the
+// compressed save.
+func unwrapSave(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(saveMagic)) {
+		// unchecked save from an older version
+		return data, nil
+	}
+	if len(data) < saveMagicLen {
+		return nil, fmt.Errorf("save file is truncated (%d bytes)", len(data))
+	}
+	n := int(binary.BigEndian.Uint32(data[len(saveMagic):]))
+	sum := binary.BigEndian.Uint32(data[len(saveMagic)+4:])
+	payload := data[saveMagicLen:]
+	if len(payload) != n {
+		return nil, fmt.Errorf("save file is truncated or has trailing data (%d bytes instead of %d)", len(payload), n)
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, errSaveCorrupted
+	}
+	return payload, nil
 }
 
 type config struct {
@@ -50,22 +319,78 @@ func (c *config) ConfigSave() ([]byte, error) {
 	return data.Bytes(), nil
 }
 
+// DecodeGameSave decodes a save, migrating it to the current save format if
+// it was written by an older version of the game.
 func (g *game) DecodeGameSave(data []byte) (*game, error) {
+	data, err := unwrapSave(data)
+	if err != nil {
+		return nil, err
+	}
 	buf := bytes.NewReader(data)
 	r, err := zlib.NewReader(buf)
 	if err != nil {
 		return nil, err
 	}
-	dec := gob.NewDecoder(r)
-	lg := &game{}
-	err = dec.Decode(lg)
+	payload, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 	r.Close()
+	h := &saveHeader{}
+	err = gob.NewDecoder(bytes.NewReader(payload)).Decode(h)
+	if err != nil {
+		return nil, err
+	}
+	if h.Format == 0 {
+		if h.Version != saveFormat0Version {
+			return nil, fmt.Errorf("save from version %s cannot be loaded: only saves from %s or later are supported", h.Version, saveFormat0Version)
+		}
+		h.Game = payload
+	}
+	if h.Format > saveFormat {
+		return nil, fmt.Errorf("save format %d (version %s) is newer than supported format %d", h.Format, h.Version, saveFormat)
+	}
+	lg := &game{}
+	err = gob.NewDecoder(bytes.NewReader(h.Game)).Decode(lg)
+	if err != nil {
+		return nil, err
+	}
+	for f := h.Format; f < saveFormat; f++ {
+		err := saveMigrations[f](lg, h.Game)
+		if err != nil {
+			return nil, fmt.Errorf("migrating save from format %d (version %s): %v", f, h.Version, err)
+		}
+	}
 	return lg, nil
 }
 
+// DecodeGameSaveBackup decodes the primary save, falling back to the backup
+// save if the primary one is missing or fails verification. A nil slice means
+// a missing save. It reports whether the backup was used, and returns the
+// primary's error in that case.
+func (g *game) DecodeGameSaveBackup(primary, backup []byte) (lg *game, usedBackup bool, err error) {
+	if primary != nil {
+		lg, err = g.DecodeGameSave(primary)
+		if err == nil {
+			return lg, false, nil
+		}
+	}
+	if backup == nil {
+		return nil, false, err
+	}
+	blg, berr := g.DecodeGameSave(backup)
+	if berr != nil {
+		if err == nil {
+			err = berr
+		}
+		return nil, false, err
+	}
+	if err == nil {
+		err = errors.New("missing save file")
+	}
+	return blg, true, err
+}
+
 func DecodeConfigSave(data []byte) (*config, error) {
 	buf := bytes.NewBuffer(data)
 	dec := gob.NewDecoder(buf)
@@ -76,3 +401,66 @@ func DecodeConfigSave(data []byte) (*config, error) {
 	}
 	return c, nil
 }
+
+func (l *dailyLedger) LedgerSave() ([]byte, error) {
+	data := bytes.Buffer{}
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(l)
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func (c *career) CareerSave() ([]byte, error) {
+	data := bytes.Buffer{}
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func (hs *highScores) HighScoresSave() ([]byte, error) {
+	data := bytes.Buffer{}
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(hs)
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func DecodeHighScores(data []byte) (*highScores, error) {
+	buf := bytes.NewBuffer(data)
+	dec := gob.NewDecoder(buf)
+	hs := &highScores{}
+	err := dec.Decode(hs)
+	if err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+func DecodeCareer(data []byte) (*career, error) {
+	buf := bytes.NewBuffer(data)
+	dec := gob.NewDecoder(buf)
+	c := &career{}
+	err := dec.Decode(c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func DecodeDailyLedger(data []byte) (*dailyLedger, error) {
+	buf := bytes.NewBuffer(data)
+	dec := gob.NewDecoder(buf)
+	l := &dailyLedger{}
+	err := dec.Decode(l)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
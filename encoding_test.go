@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"image/gif"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestSaveMigrations(t *testing.T) {
+	if len(saveMigrations) != saveFormat {
+		t.Errorf("%d save migrations for save format %d", len(saveMigrations), saveFormat)
+	}
+}
+
+func checkLoadedGame(t *testing.T, g *game) {
+	if g.Dungeon == nil || g.Player == nil {
+		t.Fatalf("missing dungeon or player")
+	}
+	if g.Depth != 1 {
+		t.Errorf("bad depth: %d", g.Depth)
+	}
+	if g.Seed == 0 {
+		t.Errorf("no seed")
+	}
+	if g.Objects.Lights == nil || g.Stats.KilledMons == nil || g.Clouds == nil {
+		t.Errorf("missing structures")
+	}
+	if len(g.Monsters) == 0 {
+		t.Errorf("no monsters")
+	}
+	if len(g.Stats.Timeline) == 0 || g.Stats.Timeline[0].Kind == "" || g.Stats.Timeline[0].Depth != 1 {
+		t.Errorf("bad timeline: %+v", g.Stats.Timeline)
+	}
+	g.md = &model{g: g}
+	g.rand = rand.New(&g.RandState)
+	for i := 0; i < 20; i++ {
+		g.EndTurn()
+		g.Player.HP = g.Player.HPMax()
+	}
+}
+
+func TestDecodeSaveFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "save-format*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no save fixtures")
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &game{}
+		lg, err := g.DecodeGameSave(data)
+		if err != nil {
+			t.Errorf("%s: %v", f, err)
+			continue
+		}
+		checkLoadedGame(t, lg)
+	}
+}
+
+func TestDecodeSaveFormat2Conducts(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "save-format2-conducts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &game{}
+	lg, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []conduct{ConductPacifist, ConductNoRest, ConductUnseen} {
+		if c.Intact(lg) {
+			t.Errorf("conduct %s not broken", c)
+		}
+	}
+	if !ConductNoMagaras.Intact(lg) {
+		t.Errorf("conduct %s broken", ConductNoMagaras)
+	}
+	if b := lg.Stats.BrokenConducts[ConductPacifist]; b.Turn == 0 || b.Depth != 1 {
+		t.Errorf("bad pacifist conduct break: %+v", b)
+	}
+	if b := lg.Stats.BrokenConducts[ConductUnseen]; b.Depth != 1 {
+		t.Errorf("bad unseen conduct break: %+v", b)
+	}
+}
+
+func TestMigrateSaveFormat3(t *testing.T) {
+	s := newSim(3)
+	g := s.g
+	if err := migrateSaveFormat3(g, nil); err != nil {
+		t.Fatal(err)
+	}
+	if g.Layout != UnknownLayout {
+		t.Errorf("bad migrated layout: %v", g.Layout)
+	}
+}
+
+func TestDecodeSaveFormat0OtherVersion(t *testing.T) {
+	g := &game{Version: "v0.4.1"}
+	gdata := bytes.Buffer{}
+	if err := gob.NewEncoder(&gdata).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(gdata.Bytes())
+	w.Close()
+	_, err := g.DecodeGameSave(buf.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "v0.4.1") {
+		t.Errorf("format 0 save from another version not refused: %v", err)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	md := &model{}
+	g := &game{md: md, Seed: 42}
+	md.g = g
+	g.InitLevel()
+	data, err := g.GameSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg, err := g.DecodeGameSave(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lg.RandState != g.RandState || lg.Seed != g.Seed {
+		t.Errorf("random state not saved")
+	}
+	checkLoadedGame(t, lg)
+}
+
+func TestSaveChecksum(t *testing.T) {
+	md := &model{}
+	g := &game{md: md, Seed: 7}
+	md.g = g
+	g.InitLevel()
+	data, err := g.GameSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.DecodeGameSave(data[:len(data)-10]); err == nil {
+		t.Errorf("truncated save not detected")
+	}
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if _, err := g.DecodeGameSave(corrupted); err != errSaveCorrupted {
+		t.Errorf("corrupted save not detected: %v", err)
+	}
+	lg, usedBackup, err := g.DecodeGameSaveBackup(corrupted, data)
+	if lg == nil || !usedBackup || err != errSaveCorrupted {
+		t.Errorf("backup not used: %v", err)
+	}
+	lg, usedBackup, err = g.DecodeGameSaveBackup(data, corrupted)
+	if lg == nil || usedBackup || err != nil {
+		t.Errorf("primary save not used: %v", err)
+	}
+}
+
+func TestSaveJSON(t *testing.T) {
+	md := &model{}
+	g := &game{md: md, Seed: 5}
+	md.g = g
+	g.InitLevel()
+	for i := 0; i < 10; i++ {
+		g.EndTurn()
+	}
+	data, err := g.GameSaveJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg, err := g.DecodeGameSaveJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ldata, err := lg.GameSaveJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, ldata) {
+		t.Errorf("JSON save changed after import")
+	}
+	checkLoadedGame(t, lg)
+}
+
+// frameReplay returns a frame replay, as written by gruid, with the given
+// frames.
+func frameReplay(t *testing.T, frames []gruid.Frame) *gruid.FrameDecoder {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	enc := gob.NewEncoder(gzw)
+	for _, fr := range frames {
+		if err := enc.Encode(fr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := gruid.NewFrameDecoder(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func TestWriteCast(t *testing.T) {
+	start := time.Unix(1000, 0)
+	st := gruid.Style{Fg: ColorRed, Attrs: AttrReverse}
+	fd := frameReplay(t, []gruid.Frame{
+		{Time: start, Width: UIWidth, Height: UIHeight, Cells: []gruid.FrameCell{
+			{P: gruid.Point{0, 0}, Cell: gruid.Cell{Rune: '@'}},
+			{P: gruid.Point{1, 0}, Cell: gruid.Cell{Rune: 'g'}},
+		}},
+		{Time: start.Add(1500 * time.Millisecond), Width: UIWidth, Height: UIHeight, Cells: []gruid.FrameCell{
+			{P: gruid.Point{5, 2}, Cell: gruid.Cell{Rune: 'x', Style: st}},
+		}},
+	})
+	buf := &bytes.Buffer{}
+	if err := WriteCast(fd, buf, "test"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("bad number of lines: %d", len(lines))
+	}
+	var hdr castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &hdr); err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != 2 || hdr.Width != UIWidth || hdr.Height != UIHeight {
+		t.Errorf("bad header: %+v", hdr)
+	}
+	var ev []interface{}
+	if err := json.Unmarshal([]byte(lines[2]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("\x1b[3;6H\x1b[0;38;5;%d;48;5;%d;7mx", Color256Red, map16ColorTo256(ColorBackground, false))
+	if ev[0] != 1.5 || ev[1] != "o" || ev[2] != want {
+		t.Errorf("bad event: %v", ev)
+	}
+}
+
+func TestWriteGIF(t *testing.T) {
+	start := time.Unix(1000, 0)
+	frames := []gruid.Frame{}
+	for i := 0; i < 10; i++ {
+		frames = append(frames, gruid.Frame{
+			Time: start.Add(time.Duration(i) * 50 * time.Millisecond), Width: UIWidth, Height: UIHeight,
+			Cells: []gruid.FrameCell{{P: gruid.Point{i, 1}, Cell: gruid.Cell{Rune: '@', Style: gruid.Style{Fg: ColorRed, Attrs: AttrInMap}}}},
+		})
+	}
+	crop, err := parseCrop("0,0,20,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteGIF(frameReplay(t, frames), buf, imageExportOptions{FPS: 10, Crop: crop}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsize := (&monochromeTileManager{}).TileSize()
+	if anim.Config.Width != 20*tsize.X || anim.Config.Height != 3*tsize.Y {
+		t.Errorf("bad size: %dx%d", anim.Config.Width, anim.Config.Height)
+	}
+	if len(anim.Image) != 6 { // every 100ms, and last frame
+		t.Errorf("bad number of frames: %d", len(anim.Image))
+	}
+	if d := anim.Delay[0]; d != 10 {
+		t.Errorf("bad delay: %d", d)
+	}
+	if _, err := parseCrop("0,0,0,3"); err == nil {
+		t.Errorf("no error for empty crop")
+	}
+}
+
+// nopModel is a model that does nothing.
+type nopModel struct{}
+
+func (nopModel) Update(msg gruid.Msg) gruid.Effect { return nil }
+func (nopModel) Draw() gruid.Grid                  { return gruid.Grid{} }
+
+func TestReplayIndex(t *testing.T) {
+	md := &model{g: &game{}}
+	g := md.g
+	buf := &bytes.Buffer{}
+	ri := newReplayIndexer(nopModel{}, md, buf)
+	g.Depth = 1
+	g.Player = &player{HP: 4}
+	g.StoryPrint("Started")
+	ri.Update(nil)
+	g.Turn = 10
+	g.StoryEntry(timelineEntry{Kind: TimelineNearDeath, Name: "guard", HP: 1, Text: "Critical hit by guard (HP: 1)"})
+	ri.Update(nil)
+	g.Depth = 2
+	g.StoryEntry(timelineEntry{Kind: TimelineAchievement, Name: string(AchStealthNovice), Text: "Achievement: " + string(AchStealthNovice)})
+	ri.Update(nil)
+	g.Turn = 20
+	g.Player.HP = 0
+	g.Stats.KilledBy = "guard"
+	ri.Update(nil)
+	marks, err := decodeReplayIndex(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []markKind{}
+	for _, m := range marks {
+		kinds = append(kinds, m.Kind)
+	}
+	expected := []markKind{markLevel, markStory, markTurn, markCritical, markLevel, markAchievement, markTurn, markDeath}
+	if fmt.Sprint(kinds) != fmt.Sprint(expected) {
+		t.Fatalf("bad kinds: %v", kinds)
+	}
+	if m := marks[3]; m.Text != "Critical hit by guard (HP: 1)" || m.Depth != 1 || m.Turn != 10 {
+		t.Errorf("bad critical mark: %+v", m)
+	}
+	if m := marks[7]; m.Text != "Killed by guard" || m.Depth != 2 || m.Turn != 20 {
+		t.Errorf("bad death mark: %+v", m)
+	}
+}
+
+func TestReplayViewer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	frames := []gruid.Frame{}
+	for i := 0; i < 10; i++ {
+		frames = append(frames, gruid.Frame{
+			Time: start.Add(time.Duration(i) * 30 * time.Second), Width: UIWidth, Height: UIHeight,
+			Cells: []gruid.FrameCell{{P: gruid.Point{i, 1}, Cell: gruid.Cell{Rune: '@'}}},
+		})
+	}
+	times, err := replayFrameTimes(frameReplay(t, frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	marks := []replayMark{
+		{Time: start.Add(-time.Second), Kind: markLevel, Depth: 1},
+		{Time: start.Add(40 * time.Second), Kind: markTurn, Depth: 1, Turn: 5},
+		{Time: start.Add(100 * time.Second), Kind: markLevel, Depth: 2, Turn: 8},
+		{Time: start.Add(200 * time.Second), Kind: markDeath, Depth: 2, Turn: 12},
+	}
+	v := newReplayViewer(frameReplay(t, frames), times, marks)
+	v.Update(gruid.MsgInit{})
+	v.Update(gruid.MsgKeyDown{Key: "p"})
+	if len(v.bookmarks) != 3 {
+		t.Fatalf("bad number of bookmarks: %d", len(v.bookmarks))
+	}
+	v.Update(gruid.MsgKeyDown{Key: "n"})
+	if v.n != 5 { // frame at 120s
+		t.Errorf("bad frame after next bookmark: %d", v.n)
+	}
+	if m, _ := v.markAt(v.time()); m.Depth != 2 || m.Turn != 8 {
+		t.Errorf("bad mark: %+v", m)
+	}
+	if gd := v.Draw(); gd.At(gruid.Point{3, 1}).Rune != '@' || gd.At(gruid.Point{5, 1}).Rune == '@' {
+		t.Errorf("bad replay grid")
+	}
+	v.Update(gruid.MsgKeyDown{Key: "N"})
+	if v.n != 1 {
+		t.Errorf("bad frame after previous bookmark: %d", v.n)
+	}
+	v.Update(gruid.MsgKeyDown{Key: "j"})
+	if v.n != 3 {
+		t.Errorf("bad frame after seek: %d", v.n)
+	}
+}
@@ -39,6 +39,10 @@ func (ev *playerEvent) Handle(g *game) {
 	switch ev.Action {
 	case StorySequence:
 		g.ComputeLOS()
+		if g.md == nil {
+			g.storyHeadless()
+			break
+		}
 		g.md.Story()
 	case AbyssFall:
 		if terrain(g.Dungeon.Cell(g.Player.P)) == ChasmCell {
@@ -134,6 +138,7 @@ const (
 	Earthquake
 	DelayedHarmonicNoiseEvent
 	DelayedOricExplosionEvent
+	BlackoutProgression
 )
 
 type posEvent struct {
@@ -171,13 +176,13 @@ func (cev *posEvent) Handle(g *game) {
 			g.StopAuto()
 		}
 		g.PushEvent(&posEvent{Action: ObstructionProgression},
-			g.Turn+DurationObstructionProgression+RandInt(DurationObstructionProgression/4))
+			g.Turn+DurationObstructionProgression+g.randInt(DurationObstructionProgression/4))
 	case FireProgression:
 		if _, ok := g.Clouds[cev.P]; !ok {
 			break
 		}
 		for _, p := range g.playerPassableNeighbors(cev.P) {
-			if RandInt(10) == 0 {
+			if g.randInt(10) == 0 {
 				continue
 			}
 			g.Burn(p)
@@ -201,7 +206,11 @@ func (cev *posEvent) Handle(g *game) {
 		p := g.FreePassableCell()
 		g.Fog(p, 1)
 		g.PushEvent(&posEvent{Action: MistProgression},
-			g.Turn+DurationMistProgression+RandInt(DurationMistProgression/4))
+			g.Turn+DurationMistProgression+g.randInt(DurationMistProgression/4))
+	case BlackoutProgression:
+		g.LightFailure()
+		g.PushEvent(&posEvent{Action: BlackoutProgression},
+			g.Turn+DurationBlackoutProgression+g.randInt(DurationBlackoutProgression/4))
 	case Earthquake:
 		g.PrintStyled("The earth suddenly shakes with force!", logSpecial)
 		g.PrintStyled("Craack!", logSpecial)
@@ -215,7 +224,7 @@ func (cev *posEvent) Handle(g *game) {
 			if !c.IsDiggable() || !g.Dungeon.HasFreeNeighbor(&g.nbs, p) {
 				continue
 			}
-			if distance(cev.P, p) > RandInt(35) || RandInt(2) == 0 {
+			if distance(cev.P, p) > g.randInt(35) || g.randInt(2) == 0 {
 				continue
 			}
 			g.Dungeon.SetCell(p, RubbleCell)
@@ -271,6 +280,30 @@ func (cev *posEvent) Handle(g *game) {
 	}
 }
 
+// LightFailure puts out a random light of the level.
+func (g *game) LightFailure() {
+	lights := []gruid.Point{}
+	for p, on := range g.Objects.Lights {
+		if on {
+			lights = append(lights, p)
+		}
+	}
+	if len(lights) == 0 {
+		return
+	}
+	sortPositions(lights)
+	p := lights[g.randInt(len(lights))]
+	g.Dungeon.SetCell(p, ExtinguishedLightCell)
+	g.Objects.Lights[p] = false
+	if g.Player.Sees(p) {
+		g.Print("A light suddenly goes out.")
+		g.StopAuto()
+	} else {
+		g.UpdateKnowledge(p, LightCell)
+	}
+	g.ComputeLOS() // recomputes lights too
+}
+
 func (g *game) NightFog(at gruid.Point, radius int) {
 	dij := &noisePath{g: g}
 	nodes := g.PR.DijkstraMap(dij, []gruid.Point{at}, radius)
@@ -295,7 +328,7 @@ func (g *game) MakeCreatureSleep(p gruid.Point) {
 		return
 	}
 	mons := g.MonsterAt(p)
-	if !mons.Exists() || (RandInt(2) == 0 && mons.Status(MonsExhausted)) {
+	if !mons.Exists() || (g.randInt(2) == 0 && mons.Status(MonsExhausted)) {
 		// do not always make already exhausted monsters sleep (they were probably awaken)
 		return
 	}
@@ -305,7 +338,7 @@ func (g *game) MakeCreatureSleep(p gruid.Point) {
 	}
 	mons.State = Resting
 	mons.Dir = ZP
-	mons.ExhaustTime(g, 4+RandInt(2))
+	mons.ExhaustTime(g, 4+g.randInt(2))
 }
 
 func (g *game) Burn(p gruid.Point) {
@@ -355,6 +388,7 @@ const (
 	DurationMagicalBarrier         = 15
 	DurationObstructionProgression = 15
 	DurationMistProgression        = 12
+	DurationBlackoutProgression    = 20
 	DurationSmokingCloakFog        = 2
 	DurationExhaustionMonster      = 10
 	DurationSatiationMonster       = 40
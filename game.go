@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"hash/fnv"
 	"log"
 	"math/rand"
 	"time"
@@ -76,7 +78,12 @@ type game struct {
 	PRauto            *paths.PathRange
 	autosources       []gruid.Point // cache
 	nbs               paths.Neighbors
+	Seed              int64  // seed of the random source (0: time based)
+	Daily             string // date of the daily challenge, if any
+	RandState         rng
 	rand              *rand.Rand
+	Layout            maplayout   // layout of the current level
+	vault             *levelVault // vault of the current level, if any (not saved)
 }
 
 type specialEvent int
@@ -86,9 +93,26 @@ const (
 	UnstableLevel
 	EarthquakeLevel
 	MistLevel
+	BlackoutLevel
 )
 
-const spEvMax = int(MistLevel)
+const spEvMax = int(BlackoutLevel)
+
+func (ev specialEvent) String() (text string) {
+	switch ev {
+	case NormalLevel:
+		text = "none"
+	case UnstableLevel:
+		text = "unstable level"
+	case EarthquakeLevel:
+		text = "earthquake"
+	case MistLevel:
+		text = "mist level"
+	case BlackoutLevel:
+		text = "blackout"
+	}
+	return text
+}
 
 type startParams struct {
 	Lore         map[int]bool
@@ -106,6 +130,7 @@ type startParams struct {
 	HealthPotion map[int]bool
 	MappingStone map[int]bool
 	CrazyImp     int
+	Vaults       map[int]bool
 }
 
 type wizardMode int
@@ -124,8 +149,8 @@ func (g *game) FreePassableCell() gruid.Point {
 		if count > maxIterations {
 			panic("FreePassableCell")
 		}
-		x := RandInt(DungeonWidth)
-		y := RandInt(DungeonHeight)
+		x := g.randInt(DungeonWidth)
+		y := g.randInt(DungeonHeight)
 		p := gruid.Point{x, y}
 		c := d.Cell(p)
 		if !c.IsPassable() {
@@ -156,27 +181,35 @@ func (g *game) GenDungeon() {
 	switch g.Depth {
 	case 2, 6, 7:
 		ml = RandomWalkCave
-		if RandInt(3) == 0 {
+		if g.randInt(3) == 0 {
 			ml = NaturalCave
 		}
 	case 4, 10, 11:
 		ml = RandomWalkTreeCave
-		if RandInt(4) == 0 && g.Depth < 11 {
+		if g.randInt(4) == 0 && g.Depth < 11 {
 			ml = RandomSmallWalkCaveUrbanised
-		} else if g.Depth == 11 && RandInt(2) == 0 {
+		} else if g.Depth == 11 && g.randInt(2) == 0 {
 			ml = RandomSmallWalkCaveUrbanised
 		}
+	case 3, 8:
+		if g.randInt(3) == 0 {
+			ml = FloodedCave
+		} else if g.randInt(10) == 0 {
+			ml = RandomSmallWalkCaveUrbanised
+		} else if g.randInt(10) == 0 {
+			ml = NaturalCave
+		}
 	case 9:
-		switch RandInt(4) {
+		switch g.randInt(4) {
 		case 0:
 			ml = NaturalCave
 		case 1:
 			ml = RandomWalkCave
 		}
 	default:
-		if RandInt(10) == 0 {
+		if g.randInt(10) == 0 {
 			ml = RandomSmallWalkCaveUrbanised
-		} else if RandInt(10) == 0 {
+		} else if g.randInt(10) == 0 {
 			ml = NaturalCave
 		}
 	}
@@ -218,9 +251,9 @@ const (
 	GenCloak
 )
 
-func PutRandomLevels(m map[int]bool, n int) {
+func (g *game) PutRandomLevels(m map[int]bool, n int) {
 	for i := 0; i < n; i++ {
-		j := 1 + RandInt(MaxDepth)
+		j := 1 + g.randInt(MaxDepth)
 		if !m[j] {
 			m[j] = true
 		} else {
@@ -255,18 +288,18 @@ func (g *game) InitFirstLevel() {
 		11: GenNothing,
 	}
 	g.Params.Lore = map[int]bool{}
-	PutRandomLevels(g.Params.Lore, 8)
+	g.PutRandomLevels(g.Params.Lore, 8)
 	g.Params.HealthPotion = map[int]bool{}
-	PutRandomLevels(g.Params.HealthPotion, 5)
+	g.PutRandomLevels(g.Params.HealthPotion, 5)
 	g.Params.MappingStone = map[int]bool{}
-	PutRandomLevels(g.Params.MappingStone, 3)
+	g.PutRandomLevels(g.Params.MappingStone, 3)
 	g.Params.Blocked = map[int]bool{}
-	if RandInt(10) > 0 {
-		g.Params.Blocked[2+RandInt(WinDepth-2)] = true
+	if g.randInt(10) > 0 {
+		g.Params.Blocked[2+g.randInt(WinDepth-2)] = true
 	}
-	if RandInt(10) == 0 {
+	if g.randInt(10) == 0 {
 		// a second one sometimes!
-		g.Params.Blocked[2+RandInt(WinDepth-2)] = true
+		g.Params.Blocked[2+g.randInt(WinDepth-2)] = true
 	}
 	g.Params.Special = []specialRoom{
 		noSpecialRoom, // unused (depth 0)
@@ -282,104 +315,111 @@ func (g *game) InitFirstLevel() {
 		roomMirrorSpecters,
 		roomArtifact,
 	}
-	if RandInt(2) == 0 {
+	if g.randInt(2) == 0 {
 		g.Params.Special[5] = roomNixes
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		if g.Params.Special[5] == roomNixes {
 			g.Params.Special[9] = roomVampires
 		} else {
 			g.Params.Special[9] = roomNixes
 		}
 	}
-	if RandInt(4) == 0 {
-		if RandInt(2) == 0 {
+	if g.randInt(4) == 0 {
+		if g.randInt(2) == 0 {
 			g.Params.Special[3] = roomFrogs
 		} else {
 			g.Params.Special[7] = roomFrogs
 		}
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		g.Params.Special[10], g.Params.Special[5] = g.Params.Special[5], g.Params.Special[10]
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		g.Params.Special[6], g.Params.Special[7] = g.Params.Special[7], g.Params.Special[6]
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		g.Params.Special[3], g.Params.Special[4] = g.Params.Special[4], g.Params.Special[3]
 	}
 	g.Params.Event = map[int]specialEvent{}
 	for i := 0; i < 2; i++ {
-		g.Params.Event[2+5*i+RandInt(5)] = specialEvent(1 + RandInt(spEvMax))
+		g.Params.Event[2+5*i+g.randInt(5)] = specialEvent(1 + g.randInt(spEvMax))
 	}
-	g.Params.Event[2+RandInt(MaxDepth-1)] = NormalLevel
+	g.Params.Event[2+g.randInt(MaxDepth-1)] = NormalLevel
 	g.Params.FakeStair = map[int]bool{}
-	if RandInt(MaxDepth) > 0 {
-		g.Params.FakeStair[2+RandInt(MaxDepth-2)] = true
-		if RandInt(MaxDepth) > MaxDepth/2 {
-			g.Params.FakeStair[2+RandInt(MaxDepth-2)] = true
-			if RandInt(MaxDepth) == 0 {
-				g.Params.FakeStair[2+RandInt(MaxDepth-2)] = true
+	if g.randInt(MaxDepth) > 0 {
+		g.Params.FakeStair[2+g.randInt(MaxDepth-2)] = true
+		if g.randInt(MaxDepth) > MaxDepth/2 {
+			g.Params.FakeStair[2+g.randInt(MaxDepth-2)] = true
+			if g.randInt(MaxDepth) == 0 {
+				g.Params.FakeStair[2+g.randInt(MaxDepth-2)] = true
 			}
 		}
 	}
 	g.Params.ExtraBanana = map[int]int{}
 	for i := 0; i < 2; i++ {
-		g.Params.ExtraBanana[1+5*i+RandInt(5)]++
+		g.Params.ExtraBanana[1+5*i+g.randInt(5)]++
 	}
 	for i := 0; i < 2; i++ {
-		g.Params.ExtraBanana[1+5*i+RandInt(5)]--
+		g.Params.ExtraBanana[1+5*i+g.randInt(5)]--
 	}
 
 	g.Params.Windows = map[int]bool{}
-	if RandInt(MaxDepth) > MaxDepth/2 {
-		g.Params.Windows[2+RandInt(MaxDepth-1)] = true
-		if RandInt(MaxDepth) == 0 {
-			g.Params.Windows[2+RandInt(MaxDepth-1)] = true
+	if g.randInt(MaxDepth) > MaxDepth/2 {
+		g.Params.Windows[2+g.randInt(MaxDepth-1)] = true
+		if g.randInt(MaxDepth) == 0 {
+			g.Params.Windows[2+g.randInt(MaxDepth-1)] = true
 		}
 	}
 	g.Params.Holes = map[int]bool{}
-	if RandInt(MaxDepth) > MaxDepth/2 {
-		g.Params.Holes[2+RandInt(MaxDepth-1)] = true
-		if RandInt(MaxDepth) == 0 {
-			g.Params.Holes[2+RandInt(MaxDepth-1)] = true
+	if g.randInt(MaxDepth) > MaxDepth/2 {
+		g.Params.Holes[2+g.randInt(MaxDepth-1)] = true
+		if g.randInt(MaxDepth) == 0 {
+			g.Params.Holes[2+g.randInt(MaxDepth-1)] = true
 		}
 	}
 	g.Params.Trees = map[int]bool{}
-	if RandInt(MaxDepth) > MaxDepth/2 {
-		g.Params.Trees[2+RandInt(MaxDepth-1)] = true
-		if RandInt(MaxDepth) == 0 {
-			g.Params.Trees[2+RandInt(MaxDepth-1)] = true
+	if g.randInt(MaxDepth) > MaxDepth/2 {
+		g.Params.Trees[2+g.randInt(MaxDepth-1)] = true
+		if g.randInt(MaxDepth) == 0 {
+			g.Params.Trees[2+g.randInt(MaxDepth-1)] = true
 		}
 	}
 	g.Params.Tables = map[int]bool{}
-	if RandInt(MaxDepth) > MaxDepth/2 {
-		g.Params.Tables[2+RandInt(MaxDepth-1)] = true
-		if RandInt(MaxDepth) == 0 {
-			g.Params.Tables[2+RandInt(MaxDepth-1)] = true
+	if g.randInt(MaxDepth) > MaxDepth/2 {
+		g.Params.Tables[2+g.randInt(MaxDepth-1)] = true
+		if g.randInt(MaxDepth) == 0 {
+			g.Params.Tables[2+g.randInt(MaxDepth-1)] = true
 		}
 	}
 	g.Params.NoMagara = map[int]bool{}
 	g.Params.NoMagara[WinDepth] = true
 	g.Params.Stones = map[int]bool{}
-	if RandInt(MaxDepth) > MaxDepth/2 {
-		g.Params.Stones[2+RandInt(MaxDepth-1)] = true
-		if RandInt(MaxDepth) == 0 {
-			g.Params.Stones[2+RandInt(MaxDepth-1)] = true
+	if g.randInt(MaxDepth) > MaxDepth/2 {
+		g.Params.Stones[2+g.randInt(MaxDepth-1)] = true
+		if g.randInt(MaxDepth) == 0 {
+			g.Params.Stones[2+g.randInt(MaxDepth-1)] = true
 		}
 	}
-	permi := RandInt(WinDepth - 1)
+	permi := g.randInt(WinDepth - 1)
 	switch permi {
 	case 0, 1, 2, 3:
 		g.GenPlan[permi+1], g.GenPlan[permi+2] = g.GenPlan[permi+2], g.GenPlan[permi+1]
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		g.GenPlan[6], g.GenPlan[7] = g.GenPlan[7], g.GenPlan[6]
 	}
-	if RandInt(4) == 0 {
+	if g.randInt(4) == 0 {
 		g.GenPlan[MaxDepth-1], g.GenPlan[MaxDepth] = g.GenPlan[MaxDepth], g.GenPlan[MaxDepth-1]
 	}
-	g.Params.CrazyImp = 2 + RandInt(MaxDepth-2)
+	g.Params.CrazyImp = 2 + g.randInt(MaxDepth-2)
+	g.Params.Vaults = map[int]bool{}
+	for i := 0; i < 2; i++ {
+		depth := 3 + g.randInt(MaxDepth-3)
+		if depth != WinDepth {
+			g.Params.Vaults[depth] = true
+		}
+	}
 	g.PR = paths.NewPathRange(gruid.NewRange(0, 0, DungeonWidth, DungeonHeight))
 	g.PRauto = paths.NewPathRange(gruid.NewRange(0, 0, DungeonWidth, DungeonHeight))
 }
@@ -411,7 +451,7 @@ var Testing = false
 
 func (g *game) InitLevel() {
 	if g.rand == nil {
-		g.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		g.InitRand()
 	}
 	// Starting data
 	if g.Depth == 0 {
@@ -420,8 +460,11 @@ func (g *game) InitLevel() {
 
 	g.InitLevelStructures()
 
-	// Dungeon terrain
+	// Dungeon terrain, generated with the level's own random source
+	grand := g.rand
+	g.rand = g.levelRand(g.Depth)
 	g.GenDungeon()
+	g.rand = grand
 
 	// Events
 	if g.Depth == 1 {
@@ -438,7 +481,7 @@ func (g *game) InitLevel() {
 	}
 	monsters := make([]*monster, len(g.Monsters))
 	copy(monsters, g.Monsters)
-	rand.Shuffle(len(monsters), func(i, j int) {
+	g.rand.Shuffle(len(monsters), func(i, j int) {
 		monsters[i], monsters[j] = monsters[j], monsters[i]
 	})
 	for _, m := range monsters {
@@ -450,18 +493,25 @@ func (g *game) InitLevel() {
 		g.StoryPrint("Special event: magically unstable level")
 		for i := 0; i < 7; i++ {
 			g.PushEvent(&posEvent{Action: ObstructionProgression},
-				g.Turn+DurationObstructionProgression+RandInt(DurationObstructionProgression/2))
+				g.Turn+DurationObstructionProgression+g.randInt(DurationObstructionProgression/2))
 		}
 	case MistLevel:
 		g.PrintStyled("The air seems dense on this level.", logSpecial)
 		g.StoryPrint("Special event: mist level")
 		for i := 0; i < 20; i++ {
 			g.PushEvent(&posEvent{Action: MistProgression},
-				g.Turn+DurationMistProgression+RandInt(DurationMistProgression/2))
+				g.Turn+DurationMistProgression+g.randInt(DurationMistProgression/2))
 		}
 	case EarthquakeLevel:
-		g.PushEvent(&posEvent{P: gruid.Point{DungeonWidth/2 - 15 + RandInt(30), DungeonHeight/2 - 5 + RandInt(10)}, Action: Earthquake},
-			g.Turn+10+RandInt(50))
+		g.PushEvent(&posEvent{P: gruid.Point{DungeonWidth/2 - 15 + g.randInt(30), DungeonHeight/2 - 5 + g.randInt(10)}, Action: Earthquake},
+			g.Turn+10+g.randInt(50))
+	case BlackoutLevel:
+		g.PrintStyled("The lights flicker ominously on this level.", logSpecial)
+		g.StoryPrint("Special event: blackout")
+		for i := 0; i < 2; i++ {
+			g.PushEvent(&posEvent{Action: BlackoutProgression},
+				g.Turn+DurationBlackoutProgression+g.randInt(DurationBlackoutProgression/2))
+		}
 
 	}
 
@@ -474,10 +524,11 @@ func (g *game) InitLevel() {
 	} else if g.Depth == MaxDepth {
 		g.PrintStyled("This the bottom floor, you now have to look for the artifact.", logSpecial)
 	}
+	addHeat(&g.Stats.DVisits, g.Depth, g.Player.P)
 	g.ComputeLOS()
 	g.MakeMonstersAware()
 	g.ComputeMonsterLOS()
-	if !Testing { // disable when testing
+	if !Testing && g.md != nil { // disable when testing
 		g.md.updateStatusInfo()
 	}
 }
@@ -532,57 +583,11 @@ const (
 
 func (g *game) Descend(style descendstyle) bool {
 	g.LevelStats()
-	if g.Stats.DUSpotted[g.Depth] < 3 {
-		AchStealthNovice.Get(g)
-	}
-	if g.Depth >= 3 {
-		if g.Stats.DRests[g.Depth] == 0 && g.Stats.DRests[g.Depth-1] == 0 {
-			AchInsomniaNovice.Get(g)
-		}
-	}
-	if g.Depth >= 5 {
-		if g.Stats.DRests[g.Depth] == 0 && g.Stats.DRests[g.Depth-1] == 0 && g.Stats.DRests[g.Depth-2] == 0 &&
-			g.Stats.DRests[g.Depth-3] == 0 {
-			AchInsomniaInitiate.Get(g)
-		}
-	}
-	if g.Depth >= 8 {
-		if g.Stats.DRests[g.Depth] == 0 && g.Stats.DRests[g.Depth-1] == 0 && g.Stats.DRests[g.Depth-2] == 0 &&
-			g.Stats.DRests[g.Depth-3] == 0 && g.Stats.DRests[g.Depth-4] == 0 && g.Stats.DRests[g.Depth-5] == 0 {
-			AchInsomniaMaster.Get(g)
-		}
-	}
-	if g.Depth >= 3 {
-		if g.Stats.DMagaraUses[g.Depth] == 0 && g.Stats.DMagaraUses[g.Depth-1] == 0 {
-			AchAntimagicNovice.Get(g)
-		}
-	}
-	if g.Depth >= 5 {
-		if g.Stats.DMagaraUses[g.Depth] == 0 && g.Stats.DMagaraUses[g.Depth-1] == 0 && g.Stats.DMagaraUses[g.Depth-2] == 0 &&
-			g.Stats.DMagaraUses[g.Depth-3] == 0 {
-			AchAntimagicInitiate.Get(g)
-		}
-	}
-	if g.Depth >= 8 {
-		if g.Stats.DMagaraUses[g.Depth] == 0 && g.Stats.DMagaraUses[g.Depth-1] == 0 && g.Stats.DMagaraUses[g.Depth-2] == 0 &&
-			g.Stats.DMagaraUses[g.Depth-3] == 0 && g.Stats.DMagaraUses[g.Depth-4] == 0 && g.Stats.DMagaraUses[g.Depth-5] == 0 {
-			AchAntimagicMaster.Get(g)
-		}
-	}
-	if g.Depth >= 5 {
-		if g.Stats.DUSpotted[g.Depth] < 3 && g.Stats.DSpotted[g.Depth-1] < 3 && g.Stats.DSpotted[g.Depth-2] < 3 {
-			AchStealthInitiate.Get(g)
-		}
-	}
-	if g.Depth >= 8 {
-		if g.Stats.DUSpotted[g.Depth] < 3 && g.Stats.DUSpotted[g.Depth-1] < 3 && g.Stats.DSpotted[g.Depth-2] < 3 &&
-			g.Stats.DSpotted[g.Depth-3] < 3 {
-			AchStealthMaster.Get(g)
-		}
-	}
+	g.CheckAchievements(CounterStealthyLevels, CounterVeryStealthyLevels, CounterRestlessLevels, CounterMagaralessLevels)
 	c := g.Dungeon.Cell(g.Player.P)
 	if terrain(c) == StairCell && g.Objects.Stairs[g.Player.P] == WinStair {
 		g.StoryPrint("Escaped!")
+		g.AchieveConducts()
 		g.ExploredLevels = g.Depth
 		g.Depth = -1
 		return true
@@ -590,10 +595,10 @@ func (g *game) Descend(style descendstyle) bool {
 	if style != DescendNormal {
 		g.md.AbyssFallAnimation()
 		g.PrintStyled("You fall into the abyss. It hurts!", logDamage)
-		g.StoryPrint("Fell into the abyss")
+		g.StoryEntry(timelineEntry{Kind: TimelineDescent, Name: "abyss", Text: "Fell into the abyss"})
 	} else {
 		g.Print("You descend deeper in the dungeon.")
-		g.StoryPrint("Descended stairs")
+		g.StoryEntry(timelineEntry{Kind: TimelineDescent, Name: "stairs", Text: "Descended stairs"})
 	}
 	g.Depth++
 	g.DepthPlayerTurn = 0
@@ -613,12 +618,11 @@ func (g *game) ApplyRest() {
 	g.Player.HPbonus = 0
 	g.Player.MP = g.Player.MPMax()
 	g.Stats.Rest++
+	ConductNoRest.Break(g)
 	g.Stats.DRests[g.Depth]++
 	g.PrintStyled("You feel fresh again after eating banana and sleeping.", logStatusEnd)
 	g.StoryPrintf("Rested in barrel (bananas: %d)", g.Player.Bananas)
-	if g.Stats.Rest == 10 {
-		AchSleepy.Get(g)
-	}
+	g.CheckAchievements(CounterRest)
 }
 
 func (g *game) AutoPlayer() bool {
@@ -697,6 +701,15 @@ func (g *game) Died() bool {
 
 type msgAuto int
 
+// Die finalizes the game's statistics after the player's death.
+func (g *game) Die() {
+	g.LevelStats()
+	if len(g.Stats.Achievements) == 0 {
+		NoAchievement.Get(g)
+	}
+	g.PrintStyled("You die...", logSpecial)
+}
+
 func (g *game) EndTurn() {
 	g.Events.Push(endTurnAction, g.Turn+DurationTurn)
 	for {
@@ -735,6 +748,32 @@ func (g *game) checks() {
 	}
 }
 
+// InitRand initializes the game's random source from the game seed. A
+// time-based seed is chosen first if none was provided, so that the game can
+// still be reproduced later.
+func (g *game) InitRand() {
+	if g.Seed == 0 {
+		g.Seed = time.Now().UnixNano()
+	}
+	g.RandState.Seed(g.Seed)
+	g.rand = rand.New(&g.RandState)
+}
+
+// levelRand returns the random source used to generate the level at a given
+// depth. It only depends on the game seed and the depth, and not on the
+// game's random source, so that levels do not depend on what the player did
+// on previous levels: everyone playing a given seed gets the same levels.
+func (g *game) levelRand(depth int) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "harmonist level %d %d", g.Seed, depth)
+	r := &rng{}
+	r.Seed(int64(h.Sum64()))
+	return rand.New(r)
+}
+
+// randInt returns a random integer in [0, n) using the game's random source.
+// It is used for everything that may affect the game's state, so that a game
+// can be reproduced from its seed.
 func (g *game) randInt(n int) int {
 	if n <= 0 {
 		return 0
@@ -1,6 +1,7 @@
 package main
 
 //import "log"
+import "strings"
 import "testing"
 import "github.com/anaseto/gruid"
 
@@ -58,7 +59,13 @@ func TestInitLevel(t *testing.T) {
 				}
 			}
 			if g.Depth != WinDepth {
-				if len(g.Objects.Magaras) != 1 {
+				nmags := len(g.Objects.Magaras)
+				if g.vault != nil && g.vault.Def.Reward == VaultRareMagara {
+					if _, ok := g.Objects.Magaras[g.vault.Reward]; ok {
+						nmags--
+					}
+				}
+				if nmags != 1 {
 					t.Errorf("bad number of magaras: %+v", g.Objects.Magaras)
 				}
 			}
@@ -144,3 +151,207 @@ func BenchmarkEndTurnPlayer(b *testing.B) {
 		}
 	}
 }
+
+func TestSeed(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		seed := int64(1 + RandInt(1<<30))
+		g1 := &game{Seed: seed, md: &model{}}
+		g2 := &game{Seed: seed, md: &model{}}
+		g1.md.g = g1
+		g2.md.g = g2
+		for depth := 0; depth < MaxDepth; depth++ {
+			g1.InitLevel()
+			g2.InitLevel()
+			it1 := g1.Dungeon.Grid.Iterator()
+			it2 := g2.Dungeon.Grid.Iterator()
+			for it1.Next() && it2.Next() {
+				if it1.Cell() != it2.Cell() {
+					t.Fatalf("seed %d, depth %d: different cells at %v", seed, g1.Depth, it1.P())
+				}
+			}
+			if len(g1.Monsters) != len(g2.Monsters) {
+				t.Fatalf("seed %d, depth %d: different number of monsters", seed, g1.Depth)
+			}
+			for j, m := range g1.Monsters {
+				if m.Kind != g2.Monsters[j].Kind || m.P != g2.Monsters[j].P {
+					t.Errorf("seed %d, depth %d: different monsters %d", seed, g1.Depth, j)
+				}
+			}
+			for j := 0; j < 20; j++ {
+				g1.EndTurn()
+				g2.EndTurn()
+				g1.Player.HP = g1.Player.HPMax()
+				g2.Player.HP = g2.Player.HPMax()
+			}
+			if g1.Player.P != g2.Player.P || g1.Turn != g2.Turn {
+				t.Errorf("seed %d, depth %d: different states after some turns", seed, g1.Depth)
+			}
+			g1.Depth++
+			g2.Depth++
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	newGame := func() *game {
+		g := &game{Player: &player{HP: 4}}
+		g.Stats.Achievements = map[achievement]int{}
+		return g
+	}
+	g := newGame()
+	g.Depth = 3
+	g.Turn = 1234
+	g.Stats.DUSpottedPerc[1] = 20
+	g.Stats.DUSpottedPerc[2] = 100
+	g.Stats.Killed = 2
+	g.Stats.Achievements[AchStealthNovice] = 10
+	sd := g.ScoreDetail()
+	expected := scoreDetail{Depth: 3000, Stealth: 800 + 0 + 1000, Achievements: 250, Kills: -400, Turns: -123}
+	if sd != expected {
+		t.Errorf("bad score detail: %+v", sd)
+	}
+	if g.Score() != 3000+1800+250-400-123 {
+		t.Errorf("bad score: %d", g.Score())
+	}
+	g = newGame()
+	g.Depth = -1
+	g.ExploredLevels = WinDepth
+	g.LiberatedShaedra = true
+	g.LiberatedArtifact = true
+	sd = g.ScoreDetail()
+	if sd.Escape != scoreEscape || sd.Shaedra != scoreShaedra || sd.Artifact != scoreArtifact || sd.Depth != WinDepth*scorePerDepth {
+		t.Errorf("bad escape score detail: %+v", sd)
+	}
+	if sd.Conducts != len(conducts)*scorePerConduct {
+		t.Errorf("bad conducts score: %+v", sd)
+	}
+	g.Player.HP = 0
+	if g.ScoreDetail().Escape != 0 {
+		t.Errorf("escape score after death")
+	}
+	g = newGame()
+	g.Depth = 1
+	g.Stats.Killed = 50
+	g.Stats.Achievements[NoAchievement] = 1
+	if sd := g.ScoreDetail(); g.Score() != 0 || sd.Achievements != 0 {
+		t.Errorf("bad negative score: %d (%+v)", g.Score(), sd)
+	}
+}
+
+func TestAchievementTable(t *testing.T) {
+	seen := map[achievement]bool{}
+	for _, def := range achievements {
+		if def.Desc == "" || len(def.Tiers) == 0 || def.Counter == NoCounter && len(def.Tiers) > 1 {
+			t.Errorf("bad achievement definition: %+v", def)
+		}
+		thresholds := map[achievementCounter]int{}
+		for _, tier := range def.Tiers {
+			if seen[tier.Ach] || tier.Ach == NoAchievement {
+				t.Errorf("bad achievement tier: %+v", tier)
+			}
+			seen[tier.Ach] = true
+			if def.Counter == NoCounter {
+				continue
+			}
+			c := def.Counter
+			if tier.Counter != NoCounter {
+				c = tier.Counter
+			}
+			if tier.Threshold <= thresholds[c] {
+				t.Errorf("achievement tiers not increasing: %+v", tier)
+			}
+			thresholds[c] = tier.Threshold
+		}
+	}
+	if len(achievementGoals) != len(seen) {
+		t.Errorf("bad number of achievement goals: %d", len(achievementGoals))
+	}
+}
+
+func TestCheckAchievements(t *testing.T) {
+	g := &game{Player: &player{HP: 4}}
+	g.Stats.Achievements = map[achievement]int{}
+	g.Depth = 1
+	g.Stats.BarrelHides = 3
+	g.CheckAchievements(CounterBarrelHides)
+	if len(g.Stats.Achievements) != 0 {
+		t.Errorf("achievement before threshold: %v", g.Stats.Achievements)
+	}
+	for _, def := range achievementGoals {
+		if def.Ach == AchBarrels {
+			if line := g.achievementLine(def).Text(); !strings.Contains(line, "Barrel Enthousiast:") || !strings.Contains(line, " 3/20 ") {
+				t.Errorf("bad progress line: %q", line)
+			}
+		}
+	}
+	g.Stats.BarrelHides = 20
+	g.CheckAchievements(CounterBarrelHides)
+	if _, ok := g.Stats.Achievements[AchBarrels]; !ok {
+		t.Errorf("no achievement at threshold")
+	}
+	g.Depth = 5
+	for depth := 3; depth <= 5; depth++ {
+		g.Stats.DExplPerc[depth] = 100
+	}
+	g.CheckAchievements(CounterExploredLevels)
+	for ach, ok := range map[achievement]bool{AchNoviceExplorer: true, AchInitiateExplorer: true, AchMasterExplorer: false} {
+		if _, got := g.Stats.Achievements[ach]; got != ok {
+			t.Errorf("%s: got %v", ach, got)
+		}
+	}
+	g.Depth = 8
+	g.Stats.DSpotted[7] = 5
+	g.Stats.DUSpotted[7] = 1
+	g.CheckAchievements(CounterStealthyLevels, CounterVeryStealthyLevels)
+	for ach, ok := range map[achievement]bool{AchStealthNovice: true, AchStealthInitiate: false, AchStealthMaster: true} {
+		if _, got := g.Stats.Achievements[ach]; got != ok {
+			t.Errorf("%s: got %v", ach, got)
+		}
+	}
+}
+
+func TestConducts(t *testing.T) {
+	g := &game{Player: &player{HP: 4}}
+	g.Stats.Achievements = map[achievement]int{}
+	g.Depth = 2
+	g.Turn = 50
+	ConductPacifist.Break(g)
+	g.Turn = 80
+	ConductPacifist.Break(g)
+	if b := g.Stats.BrokenConducts[ConductPacifist]; b.Turn != 50 || b.Depth != 2 {
+		t.Errorf("bad conduct break: %+v", b)
+	}
+	if cs := g.IntactConducts(); len(cs) != len(conducts)-1 || ConductPacifist.Intact(g) {
+		t.Errorf("bad intact conducts: %v", cs)
+	}
+	g.AchieveConducts()
+	if _, ok := g.Stats.Achievements[AchPacifist]; ok {
+		t.Errorf("achievement for broken conduct")
+	}
+	if _, ok := g.Stats.Achievements[AchUnseen]; !ok {
+		t.Errorf("no achievement for intact conduct")
+	}
+	buf := &strings.Builder{}
+	g.DumpConducts(buf)
+	if !strings.Contains(buf.String(), "broken on depth 2, turn 50") {
+		t.Errorf("bad conducts dump:\n%s", buf)
+	}
+}
+
+func TestHighScores(t *testing.T) {
+	hs := &highScores{}
+	for i := 0; i < maxHighScores; i++ {
+		if rank := hs.Add(highScore{Score: 10 * i}); rank != 1 {
+			t.Fatalf("bad rank for best score: %d", rank)
+		}
+	}
+	if rank := hs.Add(highScore{Score: 15}); rank != maxHighScores-1 {
+		t.Errorf("bad rank: %d", rank)
+	}
+	if len(hs.Entries) != maxHighScores || hs.Entries[maxHighScores-1].Score != 10 {
+		t.Errorf("bad truncation: %+v", hs.Entries)
+	}
+	if rank := hs.Add(highScore{Score: 10}); rank != 0 {
+		t.Errorf("tie with last entry ranked: %d", rank)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// levelSample contains the quantities measured on a generated level, by
+// metric name. Categorical metrics, such as bands or stone kinds, are named
+// "category: value" and count occurrences.
+type levelSample map[string]int
+
+// genStatsMetrics are the metrics measured on every level, in report order.
+// Other metrics are categorical and sorted by name after these.
+var genStatsMetrics = []string{"monsters", "dangerousness", "barrels", "lights", "fake stairs"}
+
+// sampleLevel returns the quantities measured on the current level.
+func (g *game) sampleLevel() levelSample {
+	s := levelSample{
+		"monsters":      len(g.Monsters),
+		"dangerousness": 0,
+		"barrels":       len(g.Objects.Barrels),
+		"lights":        len(g.Objects.Lights),
+		"fake stairs":   len(g.Objects.FakeStairs),
+	}
+	for _, mons := range g.Monsters {
+		s["dangerousness"] += mons.Kind.Dangerousness()
+	}
+	for _, band := range g.Bands {
+		s["band: "+band.Kind.String()]++
+	}
+	for _, stn := range g.Objects.Stones {
+		s["stone: "+stn.String()]++
+	}
+	if sr := g.Params.Special[g.Depth]; sr != noSpecialRoom {
+		s["special room: "+sr.String()]++
+	}
+	if ev := g.Params.Event[g.Depth]; ev != NormalLevel {
+		s["event: "+ev.String()]++
+	}
+	if g.vault != nil {
+		s["vault: "+g.vault.Def.Room.Name]++
+	}
+	return s
+}
+
+// allLayouts is used as layout in genStatsKey for statistics about levels of
+// any layout.
+const allLayouts maplayout = -1
+
+// genStatsKey identifies a group of levels in generation statistics.
+type genStatsKey struct {
+	Depth  int
+	Layout maplayout
+}
+
+func (k genStatsKey) layoutString() string {
+	if k.Layout == allLayouts {
+		return "all"
+	}
+	return k.Layout.String()
+}
+
+// genStats contains samples of generated levels, grouped by depth and map
+// layout.
+type genStats struct {
+	Runs    int
+	Samples map[genStatsKey][]levelSample
+}
+
+// generateStats generates n full games, with seeds seed, seed+1, and so on,
+// and collects samples for each of their levels.
+func generateStats(n int, seed int64) *genStats {
+	gs := &genStats{Runs: n, Samples: map[genStatsKey][]levelSample{}}
+	for i := 0; i < n; i++ {
+		g := &game{Seed: seed + int64(i)}
+		for {
+			g.InitLevel()
+			s := g.sampleLevel()
+			for _, k := range []genStatsKey{{g.Depth, g.Layout}, {g.Depth, allLayouts}} {
+				gs.Samples[k] = append(gs.Samples[k], s)
+			}
+			if g.Depth == MaxDepth {
+				break
+			}
+			g.Depth++
+		}
+	}
+	return gs
+}
+
+// metricSummary summarizes the distribution of a metric in a group of levels.
+type metricSummary struct {
+	Metric string
+	Mean   float64
+	Min    int
+	Max    int
+}
+
+// keys returns the groups in report order: by depth, and then by layout.
+func (gs *genStats) keys() []genStatsKey {
+	keys := []genStatsKey{}
+	for k := range gs.Samples {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		return ki.Depth < kj.Depth || ki.Depth == kj.Depth && ki.Layout < kj.Layout
+	})
+	return keys
+}
+
+// summary returns the summaries of the metrics in a group. Metrics missing
+// from a level count as zero.
+func (gs *genStats) summary(k genStatsKey) []metricSummary {
+	samples := gs.Samples[k]
+	categorical := []string{}
+	seen := map[string]bool{}
+	for _, m := range genStatsMetrics {
+		seen[m] = true
+	}
+	for _, s := range samples {
+		for m := range s {
+			if !seen[m] {
+				seen[m] = true
+				categorical = append(categorical, m)
+			}
+		}
+	}
+	sort.Strings(categorical)
+	sums := []metricSummary{}
+	for _, m := range append(append([]string{}, genStatsMetrics...), categorical...) {
+		ms := metricSummary{Metric: m, Min: samples[0][m], Max: samples[0][m]}
+		total := 0
+		for _, s := range samples {
+			total += s[m]
+			ms.Min = min(ms.Min, s[m])
+			ms.Max = max(ms.Max, s[m])
+		}
+		ms.Mean = float64(total) / float64(len(samples))
+		sums = append(sums, ms)
+	}
+	return sums
+}
+
+// WriteTable writes the statistics as human-readable tables, one per group.
+func (gs *genStats) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Runs: %d\n", gs.Runs)
+	for _, k := range gs.keys() {
+		fmt.Fprintf(tw, "\nDepth %d, layout %s (%d levels)\n", k.Depth, k.layoutString(), len(gs.Samples[k]))
+		fmt.Fprintf(tw, "mean\tmin\tmax\t metric\n")
+		for _, ms := range gs.summary(k) {
+			fmt.Fprintf(tw, "%.2f\t%d\t%d\t %s\n", ms.Mean, ms.Min, ms.Max, ms.Metric)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the statistics in CSV form, with one record per group and
+// metric.
+func (gs *genStats) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"depth", "layout", "levels", "metric", "mean", "min", "max"})
+	for _, k := range gs.keys() {
+		levels := strconv.Itoa(len(gs.Samples[k]))
+		for _, ms := range gs.summary(k) {
+			cw.Write([]string{strconv.Itoa(k.Depth), k.layoutString(), levels, ms.Metric,
+				strconv.FormatFloat(ms.Mean, 'f', 3, 64), strconv.Itoa(ms.Min), strconv.Itoa(ms.Max)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
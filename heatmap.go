@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anaseto/gruid"
+)
+
+// heatmap counts events by position on a level.
+type heatmap map[gruid.Point]int
+
+// addHeat counts an event at a position in the heatmap of a given depth.
+func addHeat(hms *[MaxDepth + 1]heatmap, depth int, p gruid.Point) {
+	if depth < 0 || depth > MaxDepth {
+		return
+	}
+	if hms[depth] == nil {
+		hms[depth] = heatmap{}
+	}
+	hms[depth][p]++
+}
+
+// heatShades are the shades used to render heatmaps, from lowest to highest
+// non-zero count.
+var heatShades = []rune{'░', '▒', '▓', '█'}
+
+// Render returns a text rendering of the heatmap, with the same frame as the
+// dungeon map in dumps. Counts are shaded relative to the highest one.
+func (hm heatmap) Render() string {
+	high := 0
+	for _, n := range hm {
+		high = max(high, n)
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "┌%s┐\n", strings.Repeat("─", DungeonWidth))
+	for y := 0; y < DungeonHeight; y++ {
+		buf.WriteRune('│')
+		for x := 0; x < DungeonWidth; x++ {
+			n := hm[gruid.Point{x, y}]
+			if n == 0 {
+				buf.WriteRune(' ')
+				continue
+			}
+			buf.WriteRune(heatShades[(n*len(heatShades)-1)/high])
+		}
+		buf.WriteString("│\n")
+	}
+	fmt.Fprintf(buf, "└%s┘\n", strings.Repeat("─", DungeonWidth))
+	return buf.String()
+}
+
+// DumpHeatmaps returns the text rendering of the visits, noise and detection
+// heatmaps of every visited level.
+func (g *game) DumpHeatmaps() string {
+	buf := &strings.Builder{}
+	maps := []struct {
+		name string
+		hms  *[MaxDepth + 1]heatmap
+	}{
+		{"visits", &g.Stats.DVisits},
+		{"noise made", &g.Stats.DNoise},
+		{"spotted", &g.Stats.DSpottedAt},
+	}
+	for depth := 1; depth <= MaxDepth; depth++ {
+		if len(g.Stats.DVisits[depth]) == 0 {
+			continue
+		}
+		for _, m := range maps {
+			hm := m.hms[depth]
+			total := 0
+			for _, n := range hm {
+				total += n
+			}
+			if total == 0 {
+				fmt.Fprintf(buf, "Depth %d, %s: none.\n", depth, m.name)
+				continue
+			}
+			fmt.Fprintf(buf, "Depth %d, %s (%d in %d cells):\n", depth, m.name, total, len(hm))
+			buf.WriteString(hm.Render())
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
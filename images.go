@@ -1,5 +1,3 @@
-// +build js sdl
-
 // font used for letters: source code pro
 
 package main
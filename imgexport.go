@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// imageExportOptions are the options for rendering replays to images.
+type imageExportOptions struct {
+	FPS  int         // maximum number of frames per second (0: no limit)
+	Crop gruid.Range // rendered cells (empty: whole grid)
+}
+
+// parseCrop parses a crop range of the form x,y,w,h in cells.
+func parseCrop(s string) (gruid.Range, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		return gruid.Range{}, fmt.Errorf("invalid crop %q: expected x,y,w,h", s)
+	}
+	var n [4]int
+	for i, f := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || v < 0 {
+			return gruid.Range{}, fmt.Errorf("invalid crop %q: %q is not a non-negative number", s, f)
+		}
+		n[i] = v
+	}
+	rg := gruid.NewRange(n[0], n[1], n[0]+n[2], n[1]+n[3])
+	if rg.Empty() {
+		return gruid.Range{}, fmt.Errorf("invalid crop %q: empty range", s)
+	}
+	return rg, nil
+}
+
+// maxImageFrameDelay is the maximum delay between two frames in animated
+// images: longer pauses in a replay are shortened.
+const maxImageFrameDelay = 2 * time.Second
+
+// tilePalette returns the palette of the colors used by the tiles.
+func tilePalette() color.Palette {
+	p := color.Palette{}
+	seen := map[color.Color]bool{}
+	for c := gruid.ColorDefault; c <= 1+15; c++ {
+		for _, fg := range []bool{false, true} {
+			cl := ColorToRGBA(c, fg)
+			if !seen[cl] {
+				seen[cl] = true
+				p = append(p, cl)
+			}
+		}
+	}
+	return p
+}
+
+// frameRenderer renders the frames of a replay with the tile set, without
+// any display.
+type frameRenderer struct {
+	opts    imageExportOptions
+	tm      monochromeTileManager
+	tsize   gruid.Point
+	palette color.Palette
+	cache   map[gruid.Cell]*image.Paletted
+	img     *image.Paletted // image of the cropped grid
+	dirty   image.Rectangle // area changed since last emitted image
+}
+
+// renderFrames decodes the frames of a replay, and calls emit with the
+// rendered image, the area changed since the previous call, and the frame's
+// time. If a frame rate limit is set, some frames are merged into the next
+// one.
+func renderFrames(fd *gruid.FrameDecoder, opts imageExportOptions,
+	emit func(img *image.Paletted, dirty image.Rectangle, t time.Time) error) error {
+	var frame gruid.Frame
+	if err := fd.Decode(&frame); err != nil {
+		if err == io.EOF {
+			return errors.New("empty replay")
+		}
+		return err
+	}
+	if opts.Crop.Empty() {
+		opts.Crop = gruid.NewRange(0, 0, frame.Width, frame.Height)
+	}
+	fr := &frameRenderer{opts: opts, cache: map[gruid.Cell]*image.Paletted{}, palette: tilePalette()}
+	fr.tsize = fr.tm.TileSize()
+	size := opts.Crop.Size()
+	fr.img = image.NewPaletted(image.Rect(0, 0, size.X*fr.tsize.X, size.Y*fr.tsize.Y), fr.palette)
+	blank := gruid.Cell{Rune: ' '}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			fr.drawCell(gruid.Point{x, y}, blank)
+		}
+	}
+	var last time.Time // time of last emitted image
+	for {
+		for _, fc := range frame.Cells {
+			if fc.P.In(opts.Crop) {
+				fr.drawCell(fc.P.Sub(opts.Crop.Min), fc.Cell)
+			}
+		}
+		if !fr.dirty.Empty() && (opts.FPS <= 0 || last.IsZero() ||
+			frame.Time.Sub(last) >= time.Second/time.Duration(opts.FPS)) {
+			if err := emit(fr.img, fr.dirty, frame.Time); err != nil {
+				return err
+			}
+			fr.dirty = image.Rectangle{}
+			last = frame.Time
+		}
+		err := fd.Decode(&frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !fr.dirty.Empty() {
+		return emit(fr.img, fr.dirty, frame.Time)
+	}
+	return nil
+}
+
+// drawCell draws a cell at a given position relative to the cropped grid.
+func (fr *frameRenderer) drawCell(p gruid.Point, c gruid.Cell) {
+	cimg, ok := fr.cache[c]
+	if !ok {
+		src := fr.tm.GetImage(c)
+		cimg = image.NewPaletted(image.Rect(0, 0, fr.tsize.X, fr.tsize.Y), fr.palette)
+		b := src.Bounds()
+		for y := 0; y < fr.tsize.Y; y++ {
+			for x := 0; x < fr.tsize.X; x++ {
+				cimg.SetColorIndex(x, y, uint8(fr.palette.Index(src.At(b.Min.X+x, b.Min.Y+y))))
+			}
+		}
+		fr.cache[c] = cimg
+	}
+	at := image.Point{p.X * fr.tsize.X, p.Y * fr.tsize.Y}
+	for y := 0; y < fr.tsize.Y; y++ {
+		i := fr.img.PixOffset(at.X, at.Y+y)
+		copy(fr.img.Pix[i:i+fr.tsize.X], cimg.Pix[y*cimg.Stride:y*cimg.Stride+fr.tsize.X])
+	}
+	fr.dirty = fr.dirty.Union(image.Rectangle{Min: at, Max: at.Add(image.Point{fr.tsize.X, fr.tsize.Y})})
+}
+
+// WriteGIF renders the frames of a replay into an animated GIF. Each GIF frame
+// only contains the area that changed since the previous one.
+func WriteGIF(fd *gruid.FrameDecoder, w io.Writer, opts imageExportOptions) error {
+	anim := &gif.GIF{}
+	var prev time.Time
+	err := renderFrames(fd, opts, func(img *image.Paletted, dirty image.Rectangle, t time.Time) error {
+		if n := len(anim.Delay); n > 0 {
+			anim.Delay[n-1] = gifDelay(t.Sub(prev))
+		}
+		prev = t
+		sub := image.NewPaletted(dirty, img.Palette)
+		for y := dirty.Min.Y; y < dirty.Max.Y; y++ {
+			copy(sub.Pix[sub.PixOffset(dirty.Min.X, y):], img.Pix[img.PixOffset(dirty.Min.X, y):img.PixOffset(dirty.Max.X, y)])
+		}
+		anim.Image = append(anim.Image, sub)
+		anim.Delay = append(anim.Delay, gifDelay(maxImageFrameDelay))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	anim.Config = image.Config{ColorModel: anim.Image[0].Palette, Width: anim.Image[0].Rect.Dx(), Height: anim.Image[0].Rect.Dy()}
+	return gif.EncodeAll(w, anim)
+}
+
+// gifDelay returns a GIF frame delay, in hundredths of second.
+func gifDelay(d time.Duration) int {
+	if d > maxImageFrameDelay {
+		d = maxImageFrameDelay
+	}
+	return int(d / (10 * time.Millisecond))
+}
+
+// WritePNGs renders the frames of a replay into a sequence of PNG files,
+// named after a given prefix and a four digit frame number. It returns the
+// number of written files.
+func WritePNGs(fd *gruid.FrameDecoder, prefix string, opts imageExportOptions) (int, error) {
+	n := 0
+	err := renderFrames(fd, opts, func(img *image.Paletted, dirty image.Rectangle, t time.Time) error {
+		n++
+		f, err := os.Create(fmt.Sprintf("%s-%04d.png", prefix, n))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(f, img); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+	return n, err
+}
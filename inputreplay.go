@@ -0,0 +1,393 @@
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// Input replays record the input messages received by the model, instead of
+// the drawn frames, so that a game can be re-simulated from its seed. They
+// are written as a stream of JSON inputReplayEntry values. Each playing
+// session starts with a session entry, followed by the session's inputs,
+// along with the game's turn and a digest of the game's state after each
+// input, so that a re-simulation can be verified.
+
+const inputReplayFormat = 1
+
+// inputReplaySession describes the start of a playing session.
+type inputReplaySession struct {
+	Format     int
+	Version    string
+	Seed       int64
+	Resumed    bool // game was loaded from a save
+	Start      time.Time
+	Keys       map[gruid.Key]action `json:",omitempty"` // custom normal mode keys
+	TargetKeys map[gruid.Key]action `json:",omitempty"` // custom target mode keys
+	Rooms      uint64               `json:",omitempty"` // digest of user room templates
+}
+
+// inputReplayEntry is either a session start or an input message.
+type inputReplayEntry struct {
+	Session *inputReplaySession `json:",omitempty"`
+	T       int64               `json:",omitempty"` // milliseconds since session start
+	Input   string              `json:",omitempty"` // key, mouse, auto or quit
+	Key     gruid.Key           `json:",omitempty"`
+	Mod     gruid.ModMask       `json:",omitempty"`
+	Action  gruid.MouseAction   `json:",omitempty"`
+	P       *gruid.Point        `json:",omitempty"`
+	N       int                 `json:",omitempty"` // turn of auto message
+	Turn    int                 `json:",omitempty"` // game turn after the input
+	Digest  uint64              `json:",omitempty"` // game state after the input
+}
+
+// inputEntry returns the entry corresponding to an input message, or false if
+// the message is not recorded.
+func inputEntry(msg gruid.Msg) (inputReplayEntry, bool) {
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		return inputReplayEntry{Input: "key", Key: msg.Key, Mod: msg.Mod}, true
+	case gruid.MsgMouse:
+		p := msg.P
+		return inputReplayEntry{Input: "mouse", Action: msg.Action, Mod: msg.Mod, P: &p}, true
+	case msgAuto:
+		return inputReplayEntry{Input: "auto", N: int(msg)}, true
+	case gruid.MsgQuit:
+		return inputReplayEntry{Input: "quit"}, true
+	}
+	return inputReplayEntry{}, false
+}
+
+// Msg returns the input message of an entry.
+func (e inputReplayEntry) Msg(t time.Time) (gruid.Msg, error) {
+	switch e.Input {
+	case "key":
+		return gruid.MsgKeyDown{Key: e.Key, Mod: e.Mod, Time: t}, nil
+	case "mouse":
+		if e.P == nil {
+			return nil, errors.New("mouse input without position")
+		}
+		return gruid.MsgMouse{Action: e.Action, Mod: e.Mod, P: *e.P, Time: t}, nil
+	case "auto":
+		return msgAuto(e.N), nil
+	case "quit":
+		return gruid.MsgQuit(t), nil
+	}
+	return nil, fmt.Errorf("unknown input: %q", e.Input)
+}
+
+// stateDigest returns a digest of the main elements of the game's state.
+func (g *game) stateDigest() uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d %d %v %d %d %d %v\n", g.Depth, g.Turn, g.Player.P, g.Player.HP,
+		g.Player.MP, g.Player.Bananas, g.Player.Magaras)
+	for _, mons := range g.Monsters {
+		fmt.Fprintf(h, "%d %v %t %d\n", mons.Kind, mons.P, mons.Dead, mons.State)
+	}
+	return h.Sum64()
+}
+
+// inputRecorder is a model wrapper that records the inputs received by the
+// game's model.
+type inputRecorder struct {
+	md    *model
+	enc   *json.Encoder
+	start time.Time
+}
+
+func newInputRecorder(md *model, w io.Writer) *inputRecorder {
+	return &inputRecorder{md: md, enc: json.NewEncoder(w)}
+}
+
+func (r *inputRecorder) Update(msg gruid.Msg) gruid.Effect {
+	if _, ok := msg.(gruid.MsgInit); ok {
+		eff := r.md.Update(msg)
+		r.start = time.Now()
+		r.enc.Encode(inputReplayEntry{Session: &inputReplaySession{
+			Format:     inputReplayFormat,
+			Version:    Version,
+			Seed:       r.md.g.Seed,
+			Resumed:    !r.md.newGame,
+			Start:      r.start,
+			Keys:       GameConfig.NormalModeKeys,
+			TargetKeys: GameConfig.TargetModeKeys,
+			Rooms:      userRoomsDigest(),
+		}})
+		return eff
+	}
+	eff := r.md.Update(msg)
+	if e, ok := inputEntry(msg); ok {
+		e.T = time.Since(r.start).Milliseconds()
+		e.Turn = r.md.g.Turn
+		e.Digest = r.md.g.stateDigest()
+		r.enc.Encode(e)
+	}
+	return eff
+}
+
+func (r *inputRecorder) Draw() gruid.Grid {
+	return r.md.Draw()
+}
+
+// IsInputReplay reports whether a file is an input replay.
+func IsInputReplay(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var e inputReplayEntry
+	err = json.NewDecoder(bufio.NewReader(f)).Decode(&e)
+	return err == nil && e.Session != nil
+}
+
+// LoadInputReplay reads the entries of an input replay file.
+func LoadInputReplay(file string) ([]inputReplayEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeInputReplay(f)
+}
+
+func decodeInputReplay(r io.Reader) ([]inputReplayEntry, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	entries := []inputReplayEntry{}
+	for {
+		var e inputReplayEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(entries) > 0 && errors.Is(err, io.ErrUnexpectedEOF) {
+				// interrupted write at the end of a session
+				break
+			}
+			return nil, fmt.Errorf("input replay entry %d: %v", len(entries), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 || entries[0].Session == nil {
+		return nil, errors.New("not an input replay")
+	}
+	s := entries[0].Session
+	if s.Format > inputReplayFormat {
+		return nil, fmt.Errorf("unsupported input replay format: %d", s.Format)
+	}
+	if s.Resumed {
+		return nil, errors.New("input replay does not start with a new game")
+	}
+	return entries, nil
+}
+
+// resim re-simulates the game of an input replay. Files written by the game
+// during the re-simulation, such as saves between sessions, go to a
+// temporary data directory.
+type resim struct {
+	entries []inputReplayEntry
+	i       int                 // next entry
+	session *inputReplaySession // current session
+	md      *model
+	now     time.Time // time of the current entry
+	dir     string
+}
+
+func newResim(entries []inputReplayEntry) (*resim, error) {
+	dir, err := ioutil.TempDir("", "harmonist-replay")
+	if err != nil {
+		return nil, err
+	}
+	dataDirOverride = dir
+	DisableAnimations = true
+	return &resim{entries: entries, dir: dir}, nil
+}
+
+// Close removes the temporary data directory.
+func (rs *resim) Close() error {
+	dataDirOverride = ""
+	return os.RemoveAll(rs.dir)
+}
+
+// Done reports whether all the entries have been re-simulated.
+func (rs *resim) Done() bool {
+	return rs.i >= len(rs.entries)
+}
+
+// Next re-simulates the next entry, and returns it.
+func (rs *resim) Next() (inputReplayEntry, error) {
+	e := rs.entries[rs.i]
+	rs.i++
+	if e.Session != nil {
+		if e.Session.Rooms != userRoomsDigest() {
+			// user room templates change level generation
+			return e, errors.New("replay recorded with other user room templates")
+		}
+		rs.session = e.Session
+		rs.now = e.Session.Start
+		GameConfig.NormalModeKeys = e.Session.Keys
+		GameConfig.TargetModeKeys = e.Session.TargetKeys
+		CustomKeys = e.Session.Keys != nil || e.Session.TargetKeys != nil
+		rs.md = &model{gd: gruid.NewGrid(UIWidth, UIHeight), g: &game{Seed: e.Session.Seed}}
+		rs.md.clock = func() time.Time { return rs.now }
+		rs.md.Update(gruid.MsgInit{})
+		return e, nil
+	}
+	if rs.md == nil {
+		return e, errors.New("input before session start")
+	}
+	rs.now = rs.session.Start.Add(time.Duration(e.T) * time.Millisecond)
+	msg, err := e.Msg(rs.now)
+	if err != nil {
+		return e, err
+	}
+	rs.md.Update(msg)
+	return e, nil
+}
+
+// Diverges reports whether the re-simulated game differs from the recorded
+// outcome of an input entry.
+func (rs *resim) Diverges(e inputReplayEntry) bool {
+	g := rs.md.g
+	return g.Turn != e.Turn || g.stateDigest() != e.Digest
+}
+
+// VerifyInputReplay re-simulates an input replay and writes a report to w. It
+// returns an error if the re-simulation diverges from the recorded outcome.
+func VerifyInputReplay(file string, w io.Writer) error {
+	entries, err := LoadInputReplay(file)
+	if err != nil {
+		return err
+	}
+	return verifyInputReplay(entries, w)
+}
+
+func verifyInputReplay(entries []inputReplayEntry, w io.Writer) error {
+	rs, err := newResim(entries)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+	s := entries[0].Session
+	fmt.Fprintf(w, "Replay of %s (seed %d)\n", s.Version, s.Seed)
+	if s.Version != Version {
+		fmt.Fprintf(w, "Warning: replay recorded by version %s, re-simulated by %s.\n", s.Version, Version)
+	}
+	inputs, sessions := 0, 0
+	for !rs.Done() {
+		e, err := rs.Next()
+		if err != nil {
+			return fmt.Errorf("entry %d: %v", rs.i-1, err)
+		}
+		if e.Session != nil {
+			sessions++
+			continue
+		}
+		inputs++
+		if rs.Diverges(e) {
+			g := rs.md.g
+			return fmt.Errorf("re-simulation diverges at turn %d (session %d, input %d): re-simulated turn %d",
+				e.Turn, sessions, inputs, g.Turn)
+		}
+	}
+	fmt.Fprintf(w, "Verified %d inputs in %d sessions: last turn %d, depth %d.\n", inputs, sessions, rs.md.g.Turn, rs.md.g.Depth)
+	return nil
+}
+
+// inputReplayViewer is a model that shows the re-simulation of an input
+// replay, with the original timing of inputs.
+type inputReplayViewer struct {
+	rs     *resim
+	gd     gruid.Grid
+	speed  int // speed factor
+	paused bool
+	step   int  // index of the last scheduled step
+	wait   bool // step scheduled and not yet done
+	err    error
+}
+
+// msgReplayStep asks for the re-simulation of the next entry.
+type msgReplayStep int
+
+func (v *inputReplayViewer) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgInit:
+		return v.schedule()
+	case gruid.MsgQuit:
+		return gruid.End()
+	case gruid.MsgKeyDown:
+		switch msg.Key {
+		case "q", "Q", gruid.KeyEscape:
+			return gruid.End()
+		case "p", "P", gruid.KeySpace:
+			v.paused = !v.paused
+		case "+":
+			if v.speed < 64 {
+				v.speed *= 2
+			}
+		case "-":
+			if v.speed > 1 {
+				v.speed /= 2
+			}
+		}
+		return v.schedule()
+	case msgReplayStep:
+		if int(msg) != v.step {
+			return nil
+		}
+		v.wait = false
+		if v.paused || v.rs.Done() || v.err != nil {
+			return nil
+		}
+		_, v.err = v.rs.Next()
+		return v.schedule()
+	}
+	return nil
+}
+
+// schedule schedules the next step, if needed.
+func (v *inputReplayViewer) schedule() gruid.Effect {
+	if v.wait || v.paused || v.rs.Done() || v.err != nil {
+		return nil
+	}
+	var delay time.Duration
+	if e := v.rs.entries[v.rs.i]; e.Session == nil && v.rs.session != nil {
+		delay = v.rs.session.Start.Add(time.Duration(e.T) * time.Millisecond).Sub(v.rs.now)
+		if delay > maxReplayDelay {
+			delay = maxReplayDelay
+		}
+		delay /= time.Duration(v.speed)
+	}
+	v.step++
+	v.wait = true
+	n := v.step
+	return gruid.Cmd(func() gruid.Msg {
+		time.Sleep(delay)
+		return msgReplayStep(n)
+	})
+}
+
+func (v *inputReplayViewer) Draw() gruid.Grid {
+	if v.rs.md == nil {
+		return v.gd
+	}
+	gd := v.rs.md.Draw()
+	if v.err != nil {
+		line := gd.Slice(gd.Range().Line(UIHeight - 1))
+		line.Fill(gruid.Cell{Rune: ' '})
+		ui.Text(fmt.Sprintf("Replay error: %v", v.err)).Draw(line)
+	}
+	return gd
+}
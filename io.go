@@ -8,9 +8,19 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 )
 
+// dataDirOverride replaces the data directory when not empty. It is used
+// when re-simulating input replays, so that the player's files are left
+// untouched.
+var dataDirOverride string
+
 func DataDir() (string, error) {
+	if dataDirOverride != "" {
+		return dataDirOverride, nil
+	}
 	var xdg string
 	if runtime.GOOS == "windows" {
 		xdg = os.Getenv("LOCALAPPDATA")
@@ -31,7 +41,19 @@ func DataDir() (string, error) {
 	return dataDir, nil
 }
 
+// SaveFile atomically writes data to a file in the data directory: data is
+// first written and synced to a temporary file, which is then renamed.
 func SaveFile(filename string, data []byte) error {
+	return saveFile(filename, data, false)
+}
+
+// SaveFileBackup is like SaveFile, but the previous version of the file, if
+// any, is kept as a backup with a ".bak" suffix.
+func SaveFileBackup(filename string, data []byte) error {
+	return saveFile(filename, data, true)
+}
+
+func saveFile(filename string, data []byte, backup bool) error {
 	dataDir, err := DataDir()
 	if err != nil {
 		return err
@@ -42,20 +64,41 @@ func SaveFile(filename string, data []byte) error {
 		return err
 	}
 	_, err = f.Write(data)
-	if err != nil {
-		return err
+	if err == nil {
+		err = f.Sync()
 	}
-	if err := f.Sync(); err != nil {
-		return err
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	if err := f.Close(); err != nil {
+	if err != nil {
+		os.Remove(tempSaveFile)
 		return err
 	}
 	saveFile := filepath.Join(dataDir, filename)
-	if err := os.Rename(f.Name(), saveFile); err != nil {
+	if backup {
+		_, err := os.Stat(saveFile)
+		if err == nil {
+			if err := os.Rename(saveFile, saveFile+".bak"); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(tempSaveFile, saveFile); err != nil {
 		return err
 	}
-	return err
+	syncDir(dataDir)
+	return nil
+}
+
+// syncDir syncs a directory, so that renames within it are durable. Errors are
+// ignored, as not all systems support it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
 }
 
 func (g *game) Save() error {
@@ -64,7 +107,7 @@ func (g *game) Save() error {
 		g.Print(err.Error())
 		return err
 	}
-	err = SaveFile("save", data)
+	err = SaveFileBackup("save", data)
 	if err != nil {
 		g.Print(err.Error())
 	}
@@ -72,39 +115,58 @@ func (g *game) Save() error {
 }
 
 func RemoveSaveFile() error {
-	return RemoveDataFile("save")
+	err := RemoveDataFile("save")
+	if err != nil {
+		return err
+	}
+	return RemoveDataFile("save.bak")
 }
 
 func RemoveReplay() {
 	RemoveDataFile("replay.part")
+	RemoveDataFile("replay.part.index")
+	RemoveDataFile("inputreplay.part")
 }
 
+// Load loads the saved game, if any. If the save is corrupted, it falls back
+// to the backup of the previous save: in that case, it returns true along
+// with the error of the primary save.
 func (g *game) Load() (bool, error) {
 	dataDir, err := DataDir()
 	if err != nil {
 		return false, err
 	}
 	saveFile := filepath.Join(dataDir, "save")
-	_, err = os.Stat(saveFile)
-	if err != nil {
-		// no save file, new game
-		return false, nil
-	}
-	data, err := ioutil.ReadFile(saveFile)
+	data, err := readDataFile(saveFile)
 	if err != nil {
 		return false, err
 	}
-	lg, err := g.DecodeGameSave(data)
-	if err != nil {
-		return false, err
+	backup, berr := readDataFile(saveFile + ".bak")
+	if data == nil && backup == nil {
+		// no save file, new game
+		return false, berr
 	}
-	if lg.Version != Version {
-		return false, nil
+	lg, usedBackup, err := g.DecodeGameSaveBackup(data, backup)
+	if lg == nil {
+		return false, err
 	}
 	*g = *lg
+	if usedBackup {
+		return true, err
+	}
 	return true, nil
 }
 
+// readDataFile reads a file, returning nil without error if it does not
+// exist.
+func readDataFile(file string) ([]byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
 func SaveConfig() error {
 	data, err := GameConfig.ConfigSave()
 	if err != nil {
@@ -139,6 +201,87 @@ func LoadConfig() (bool, error) {
 	return true, nil
 }
 
+func SaveDailyLedger(l *dailyLedger) error {
+	data, err := l.LedgerSave()
+	if err != nil {
+		return err
+	}
+	return SaveFile("daily.gob", data)
+}
+
+// LoadDailyLedger loads the daily challenge results. It returns an empty
+// ledger if no daily challenge was played yet.
+func LoadDailyLedger() (*dailyLedger, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	ledgerFile := filepath.Join(dataDir, "daily.gob")
+	_, err = os.Stat(ledgerFile)
+	if err != nil {
+		return &dailyLedger{}, nil
+	}
+	data, err := ioutil.ReadFile(ledgerFile)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDailyLedger(data)
+}
+
+func SaveCareer(c *career) error {
+	data, err := c.CareerSave()
+	if err != nil {
+		return err
+	}
+	return SaveFile("career.gob", data)
+}
+
+// LoadCareer loads the career statistics. It returns an empty career if no
+// game was finished yet.
+func LoadCareer() (*career, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	careerFile := filepath.Join(dataDir, "career.gob")
+	_, err = os.Stat(careerFile)
+	if err != nil {
+		return &career{}, nil
+	}
+	data, err := ioutil.ReadFile(careerFile)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeCareer(data)
+}
+
+func SaveHighScores(hs *highScores) error {
+	data, err := hs.HighScoresSave()
+	if err != nil {
+		return err
+	}
+	return SaveFile("scores.gob", data)
+}
+
+// LoadHighScores loads the high-score table. It returns an empty table if no
+// game was finished yet.
+func LoadHighScores() (*highScores, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	scoresFile := filepath.Join(dataDir, "scores.gob")
+	_, err = os.Stat(scoresFile)
+	if err != nil {
+		return &highScores{}, nil
+	}
+	data, err := ioutil.ReadFile(scoresFile)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeHighScores(data)
+}
+
 func RemoveDataFile(file string) error {
 	dataDir, err := DataDir()
 	if err != nil {
@@ -155,6 +298,109 @@ func RemoveDataFile(file string) error {
 	return nil
 }
 
+// morgueFiles are the data files of a finished game that are archived in the
+// morgue, if present.
+var morgueFiles = []string{"dump", "dump.json", "replay", "replay.index", "inputreplay"}
+
+// ArchiveGame copies the dump and replays of the last finished game into a
+// new morgue directory named after a given time.
+func ArchiveGame(t time.Time) error {
+	dataDir, err := DataDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(dataDir, "morgue", t.Format(morgueTimeFormat))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, file := range morgueFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dataDir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadMorgue returns the archived games, most recent first.
+func LoadMorgue() ([]morgueEntry, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(filepath.Join(dataDir, "morgue"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []morgueEntry{}
+	for i := len(fis) - 1; i >= 0; i-- {
+		if !fis[i].IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dataDir, "morgue", fis[i].Name(), "dump.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		entries = append(entries, newMorgueEntry(fis[i].Name(), data))
+	}
+	return entries, nil
+}
+
+// LoadMorgueDump returns the text dump of an archived game.
+func LoadMorgueDump(name string) (string, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "morgue", name, "dump"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LoadRoomTemplates returns the room templates found in the rooms
+// directory, named after their file, without the ".room" extension.
+func LoadRoomTemplates() ([]roomTemplate, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(dataDir, "rooms")
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tpls := []roomTemplate{}
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".room" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		tpl, err := ParseRoomTemplate(strings.TrimSuffix(fi.Name(), ".room"), data)
+		if err != nil {
+			return nil, err
+		}
+		tpls = append(tpls, tpl)
+	}
+	return tpls, nil
+}
+
 func (g *game) WriteDump() error {
 	dataDir, err := DataDir()
 	if err != nil {
@@ -164,5 +410,13 @@ func (g *game) WriteDump() error {
 	if err != nil {
 		return fmt.Errorf("writing dump statistics: %v", err)
 	}
+	data, err := g.DumpJSON()
+	if err != nil {
+		return fmt.Errorf("encoding JSON dump: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dataDir, "dump.json"), data, 0644)
+	if err != nil {
+		return fmt.Errorf("writing JSON dump: %v", err)
+	}
 	return nil
 }
@@ -0,0 +1,281 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestLoadBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmonist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", dir)
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	md := &model{}
+	g := &game{md: md, Seed: 3}
+	md.g = g
+	g.InitLevel()
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+	g.Turn = 42
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saveFile := filepath.Join(dir, "harmonist", "save")
+	if _, err := os.Stat(saveFile + ".bak"); err != nil {
+		t.Fatalf("no backup: %v", err)
+	}
+	lg := &game{}
+	load, err := lg.Load()
+	if !load || err != nil || lg.Turn != 42 {
+		t.Errorf("bad load: %v %v %d", load, err, lg.Turn)
+	}
+	data, err := ioutil.ReadFile(saveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := ioutil.WriteFile(saveFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lg = &game{}
+	load, err = lg.Load()
+	if !load || err == nil || lg.Turn != 0 {
+		t.Errorf("backup not loaded: %v %v %d", load, err, lg.Turn)
+	}
+	if err := RemoveSaveFile(); err != nil {
+		t.Fatal(err)
+	}
+	lg = &game{}
+	if load, err := lg.Load(); load || err != nil {
+		t.Errorf("save not removed: %v %v", load, err)
+	}
+}
+
+func TestInputReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmonist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xdg := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", dir)
+	defer os.Setenv("XDG_DATA_HOME", xdg)
+	buf := &bytes.Buffer{}
+	play := func(seed int64, keys string) {
+		md := &model{gd: gruid.NewGrid(UIWidth, UIHeight), g: &game{Seed: seed}}
+		r := newInputRecorder(md, buf)
+		r.Update(gruid.MsgInit{})
+		r.Update(gruid.MsgKeyDown{Key: gruid.KeySpace})
+		for _, k := range keys {
+			r.Update(gruid.MsgKeyDown{Key: gruid.Key(k)})
+		}
+		r.Update(gruid.MsgQuit{})
+	}
+	play(5, "hhjjkklls")
+	play(0, "llllz")
+	entries, err := decodeInputReplay(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 12+8 || entries[12].Session == nil || !entries[12].Session.Resumed {
+		t.Fatalf("bad entries: %d", len(entries))
+	}
+	if err := verifyInputReplay(entries, ioutil.Discard); err != nil {
+		t.Errorf("verification: %v", err)
+	}
+	entries[0].Session.Rooms++
+	if err := verifyInputReplay(entries, ioutil.Discard); err == nil {
+		t.Errorf("no error for other user room templates")
+	}
+	entries[0].Session.Rooms--
+	entries[len(entries)-3].Digest++
+	if err := verifyInputReplay(entries, ioutil.Discard); err == nil {
+		t.Errorf("no divergence found")
+	}
+}
+
+func TestMorgue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmonist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dataDirOverride = dir
+	defer func() { dataDirOverride = "" }()
+	if entries, err := LoadMorgue(); len(entries) != 0 || err != nil {
+		t.Fatalf("bad empty morgue: %v %v", entries, err)
+	}
+	s := newSim(4)
+	g := s.g
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	for i := 0; i < 2; i++ {
+		g.Turn = 100 * (i + 1)
+		if i == 1 {
+			g.Player.HP = 0
+		}
+		if err := g.WriteDump(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ArchiveGame(start.Add(time.Duration(i) * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := LoadMorgue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("bad number of entries: %d", len(entries))
+	}
+	if e := entries[0]; !e.Known || !e.Outcome.Died || e.Outcome.Turns != 200 || !e.Time.Equal(start.Add(time.Hour)) {
+		t.Errorf("bad last entry: %+v", e)
+	}
+	if e := entries[1]; e.Summary() != "2026-01-02 03:04 abandoned depth  1,   100 turns" {
+		t.Errorf("bad summary: %q", e.Summary())
+	}
+	dump, err := LoadMorgueDump(entries[1].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump, "You spent 100 turns") {
+		t.Errorf("bad archived dump")
+	}
+	if len(entries[1].Timeline) != len(g.Stats.Timeline) {
+		t.Errorf("bad archived timeline: %+v", entries[1].Timeline)
+	}
+	d := dumpView{lines: strings.Split(dump, "\n"), timeline: entries[1].Timeline, filterable: true}
+	all, tl := d.Lines()
+	if tl < 0 || all[tl+1] != g.Stats.Timeline[0].String() {
+		t.Fatalf("no timeline in dump: %d", tl)
+	}
+	for i, kind := range timelineKinds {
+		d.filter = i + 1
+		lines, _ := d.Lines()
+		n := len(filterTimeline(g.Stats.Timeline, kind))
+		if len(lines) != len(all)-len(g.Stats.Timeline)+max(n, 1) {
+			t.Errorf("bad %s timeline: %d lines with %d entries", kind, len(lines), n)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "morgue", entries[0].Name, "replay")); !os.IsNotExist(err) {
+		t.Errorf("missing replay archived: %v", err)
+	}
+}
+
+func TestDumpTimelineFilter(t *testing.T) {
+	s := newSim(4)
+	md := &model{g: s.g}
+	md.initWidgets()
+	md.mode = modeDump
+	md.dump(errors.New("no dump file"))
+	all, tl := md.dumpView.Lines()
+	if tl < 0 || len(all) != tl+1+len(s.g.Stats.Timeline) {
+		t.Fatalf("no timeline in end of game dump: %d", tl)
+	}
+	md.updateDump(gruid.MsgKeyDown{Key: ">"})
+	if md.dumpView.filter != 1 || md.mode != modeDump {
+		t.Errorf("filter key not handled: %d", md.dumpView.filter)
+	}
+	lines, _ := md.dumpView.Lines()
+	n := len(filterTimeline(s.g.Stats.Timeline, timelineKinds[0]))
+	if len(lines) != tl+1+max(n, 1) {
+		t.Errorf("bad filtered timeline: %d lines with %d entries", len(lines), n)
+	}
+}
+
+func TestCareer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmonist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dataDirOverride = dir
+	defer func() { dataDirOverride = "" }()
+	g := newSim(4).g
+	g.Stats.UsedMagaras[BlinkMagara] = 2
+	AchStealthNovice.Get(g)
+	g.Player.HP = 0
+	g.Stats.KilledBy = "guard"
+	if err := g.RecordCareer(time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	g = newSim(5).g
+	AchStealthNovice.Get(g)
+	g.Depth = -1
+	g.LiberatedShaedra = true
+	if err := g.RecordCareer(time.Date(2026, 1, 3, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	g.Wizard = true
+	if err := g.RecordCareer(time.Date(2026, 1, 4, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCareer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Runs != 2 || c.Wins != 1 || c.Shaedra != 1 || c.Artifacts != 0 {
+		t.Errorf("bad totals: %+v", c)
+	}
+	if c.KilledBy["guard"] != 1 || c.DeathDepths[1] != 1 || c.MagaraUses["magara of blinking"] != 2 {
+		t.Errorf("bad details: %+v", c)
+	}
+	if date := c.Achievements[string(AchStealthNovice)]; date != "2026-01-02" {
+		t.Errorf("bad achievement date: %s", date)
+	}
+	pages := c.Pages()
+	if len(pages) != 4 || len(pages[3].Lines) != len(achievementGoals) {
+		t.Errorf("bad pages: %d", len(pages))
+	}
+}
+
+func TestLoadRoomTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmonist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dataDirOverride = dir
+	defer func() { dataDirOverride = "" }()
+	tpls, err := LoadRoomTemplates()
+	if err != nil || len(tpls) != 0 {
+		t.Fatalf("no rooms directory: %v %v", tpls, err)
+	}
+	rdir := filepath.Join(dir, "rooms")
+	if err := os.Mkdir(rdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rdir, "hut.room"), []byte("kind: big\n\n#+##\n#.>#\n####\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rdir, "README"), []byte("not a room"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tpls, err = LoadRoomTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpls) != 1 || tpls[0].Name != "hut" || tpls[0].Kind != roomBig {
+		t.Errorf("bad templates: %+v", tpls)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rdir, "bad.room"), []byte("kind: huge\n\n#+#\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRoomTemplates(); err == nil {
+		t.Errorf("no error for bad template")
+	}
+}
@@ -54,7 +54,9 @@ func main() {
 		}
 		switch mainMenu.action {
 		case MainPlayGame:
-			mainMenu.err = RunGame()
+			mainMenu.err = RunGame(false)
+		case MainDailyGame:
+			mainMenu.err = RunGame(true)
 		case MainReplayGame:
 			mainMenu.err = RunReplay()
 		}
@@ -67,6 +69,7 @@ type mainMenuAction int
 const (
 	MainMenuDefault mainMenuAction = iota
 	MainPlayGame
+	MainDailyGame
 	MainReplayGame
 )
 
@@ -88,6 +91,7 @@ func newMainMenu() *mainMenu {
 		Grid: gruid.NewGrid(UIWidth/2, 3),
 		Entries: []ui.MenuEntry{
 			{Text: ui.Text("- (P)lay"), Keys: []gruid.Key{"p", "P"}},
+			{Text: ui.Text("- (D)aily challenge"), Keys: []gruid.Key{"d", "D"}},
 			{Text: ui.Text("- (R)eplay"), Keys: []gruid.Key{"r", "R"}},
 		},
 		Style: style,
@@ -113,6 +117,9 @@ func (md *mainMenu) Update(msg gruid.Msg) gruid.Effect {
 			md.action = MainPlayGame
 			return gruid.End()
 		case 1:
+			md.action = MainDailyGame
+			return gruid.End()
+		case 2:
 			md.action = MainReplayGame
 			return gruid.End()
 		}
@@ -134,9 +141,9 @@ func (md *mainMenu) Draw() gruid.Grid {
 const repit = "harmonistreplay"
 const replock = "harmonistreplock"
 
-func RunGame() error {
+func RunGame(daily bool) error {
 	gd := gruid.NewGrid(UIWidth, UIHeight)
-	m := &model{gd: gd, g: &game{}}
+	m := &model{gd: gd, g: &game{}, daily: daily}
 	repw := &bytes.Buffer{}
 	defer func() {
 		if m.finished {
@@ -252,12 +259,23 @@ func RemoveItem(item string) {
 
 const harmonistsave = "harmonistsave"
 const harmonistconfig = "harmonistconfig"
+const harmonistdaily = "harmonistdaily"
+const harmonistcareer = "harmonistcareer"
+const harmonistscores = "harmonistscores"
+const harmonistsavebackup = "harmonistsave.bak"
 
 func (g *game) Save() error {
 	save, err := g.GameSave()
 	if err != nil {
 		return err
 	}
+	old, err := GetItem(harmonistsave)
+	if err == nil && old != nil {
+		err = SetItem(harmonistsavebackup, old)
+		if err != nil {
+			return err
+		}
+	}
 	err = SetItem(harmonistsave, save)
 	if err != nil {
 		return err
@@ -279,6 +297,7 @@ func SaveConfig() error {
 
 func RemoveSaveFile() error {
 	RemoveItem(harmonistsave)
+	RemoveItem(harmonistsavebackup)
 	return nil
 }
 
@@ -289,17 +308,21 @@ func RemoveReplay() {
 
 func (g *game) Load() (bool, error) {
 	s, err := GetItem(harmonistsave)
-	if err != nil || s == nil {
-		return false, nil
-	}
-	lg, err := g.DecodeGameSave(s)
 	if err != nil {
-		return false, err
+		return false, nil
 	}
-	if lg.Version != Version {
+	backup, _ := GetItem(harmonistsavebackup)
+	if s == nil && backup == nil {
 		return false, nil
 	}
+	lg, usedBackup, err := g.DecodeGameSaveBackup(s, backup)
+	if lg == nil {
+		return false, err
+	}
 	*g = *lg
+	if usedBackup {
+		return true, err
+	}
 	return true, nil
 }
 
@@ -319,6 +342,75 @@ func LoadConfig() (bool, error) {
 	return true, nil
 }
 
+func SaveDailyLedger(l *dailyLedger) error {
+	data, err := l.LedgerSave()
+	if err != nil {
+		return err
+	}
+	return SetItem(harmonistdaily, data)
+}
+
+func LoadDailyLedger() (*dailyLedger, error) {
+	s, err := GetItem(harmonistdaily)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return &dailyLedger{}, nil
+	}
+	return DecodeDailyLedger(s)
+}
+
+func SaveCareer(c *career) error {
+	data, err := c.CareerSave()
+	if err != nil {
+		return err
+	}
+	return SetItem(harmonistcareer, data)
+}
+
+func LoadCareer() (*career, error) {
+	s, err := GetItem(harmonistcareer)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return &career{}, nil
+	}
+	return DecodeCareer(s)
+}
+
+func SaveHighScores(hs *highScores) error {
+	data, err := hs.HighScoresSave()
+	if err != nil {
+		return err
+	}
+	return SetItem(harmonistscores, data)
+}
+
+func LoadHighScores() (*highScores, error) {
+	s, err := GetItem(harmonistscores)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return &highScores{}, nil
+	}
+	return DecodeHighScores(s)
+}
+
+func LoadMorgue() ([]morgueEntry, error) {
+	return nil, errors.New("game history not available in the browser")
+}
+
+func LoadMorgueDump(name string) (string, error) {
+	return "", errors.New("game history not available in the browser")
+}
+
+func LoadRoomTemplates() ([]roomTemplate, error) {
+	return nil, nil
+}
+
 func (g *game) WriteDump() error {
 	pre := js.Global().Get("document").Call("getElementById", "dump")
 	pre.Set("innerHTML", g.Dump())
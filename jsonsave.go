@@ -0,0 +1,618 @@
+package main
+
+// This file implements a human-readable JSON form of saved games, meant for
+// inspecting and hand-editing game states, for example to build bug
+// reproductions or test fixtures.
+//
+// The JSON form is an object with the save format number (saveFormat), the
+// game version and the game itself:
+//
+//	{"Format": <saveFormat>, "Version": "<Version>", "Game": {...}}
+//
+// The game mirrors the game structure: structures are objects with their
+// exported fields in declaration order, slices and arrays are arrays, and
+// numeric types (including enumerations such as monster kinds or states) are
+// plain numbers. The following types have a special form:
+//
+//   - positions are [x, y] arrays.
+//   - maps are objects: position keys are written "x,y", other keys are
+//     written as numbers or strings.
+//   - cells are cell names, such as "Ground" or "Wall", that is the cell
+//     constants without the Cell suffix. Explored cells have a "*" suffix.
+//   - the dungeon is an array of rows, each row being an array of cell names.
+//   - the event queue is an array of events in the order they will happen.
+//     Each event is an object with the event's Rank (the turn at which it
+//     happens), Type (for example "monsterTurnEvent") and Event fields.
+//
+// Fields of field of view and path range types are caches and are omitted:
+// they are computed again on import.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/paths"
+	"github.com/anaseto/gruid/rl"
+)
+
+// jsonSave is the top-level structure of the JSON form of a save.
+type jsonSave struct {
+	Format  int
+	Version string
+	Game    json.RawMessage
+}
+
+var cellNames = [...]string{
+	WallCell:              "Wall",
+	GroundCell:            "Ground",
+	DoorCell:              "Door",
+	FoliageCell:           "Foliage",
+	BarrelCell:            "Barrel",
+	StairCell:             "Stair",
+	StoneCell:             "Stone",
+	MagaraCell:            "Magara",
+	BananaCell:            "Banana",
+	LightCell:             "Light",
+	ExtinguishedLightCell: "ExtinguishedLight",
+	TableCell:             "Table",
+	TreeCell:              "Tree",
+	HoledWallCell:         "HoledWall",
+	ScrollCell:            "Scroll",
+	StoryCell:             "Story",
+	ItemCell:              "Item",
+	BarrierCell:           "Barrier",
+	WindowCell:            "Window",
+	ChasmCell:             "Chasm",
+	WaterCell:             "Water",
+	RubbleCell:            "Rubble",
+	CavernCell:            "Cavern",
+	FakeStairCell:         "FakeStair",
+	PotionCell:            "Potion",
+	QueenRockCell:         "QueenRock",
+}
+
+func cellName(c cell) string {
+	t := terrain(c)
+	name := fmt.Sprintf("%d", t)
+	if int(t) < len(cellNames) {
+		name = cellNames[t]
+	}
+	if explored(c) {
+		name += "*"
+	}
+	return name
+}
+
+func parseCellName(s string) (cell, error) {
+	var c cell
+	if strings.HasSuffix(s, "*") {
+		c |= Explored
+		s = strings.TrimSuffix(s, "*")
+	}
+	for i, name := range cellNames {
+		if name == s {
+			return c | cell(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cell name: %q", s)
+}
+
+var (
+	pointType      = reflect.TypeOf(gruid.Point{})
+	cellType       = reflect.TypeOf(cell(0))
+	dungeonType    = reflect.TypeOf(&dungeon{})
+	eventQueueType = reflect.TypeOf(&rl.EventQueue{})
+	fovType        = reflect.TypeOf(&rl.FOV{})
+	pathRangeType  = reflect.TypeOf(&paths.PathRange{})
+)
+
+// eventType returns the type name of an event in the JSON form.
+func eventType(ev rl.Event) string {
+	t := reflect.TypeOf(ev)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// jsonObject is a JSON object whose fields are written in order.
+type jsonObject []jsonField
+
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// GameSaveJSON returns the JSON form of the game.
+func (g *game) GameSaveJSON() ([]byte, error) {
+	gv, err := toJSONValue(reflect.ValueOf(g).Elem())
+	if err != nil {
+		return nil, err
+	}
+	s := jsonObject{
+		{"Format", saveFormat},
+		{"Version", Version},
+		{"Game", gv},
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.Buffer{}
+	err = json.Indent(&buf, data, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// DecodeGameSaveJSON decodes a game from its JSON form.
+func (g *game) DecodeGameSaveJSON(data []byte) (*game, error) {
+	s := jsonSave{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Format != saveFormat {
+		return nil, fmt.Errorf("JSON save has format %d (version %s), but only format %d is supported", s.Format, s.Version, saveFormat)
+	}
+	if s.Game == nil {
+		return nil, fmt.Errorf("JSON save has no game")
+	}
+	dec := json.NewDecoder(bytes.NewReader(s.Game))
+	dec.UseNumber()
+	var x interface{}
+	if err := dec.Decode(&x); err != nil {
+		return nil, err
+	}
+	lg := &game{}
+	if err := fromJSONValue(x, reflect.ValueOf(lg).Elem(), "Game"); err != nil {
+		return nil, err
+	}
+	if lg.Dungeon == nil || lg.Player == nil {
+		return nil, fmt.Errorf("JSON save has no dungeon or player")
+	}
+	if lg.Events == nil {
+		lg.Events = rl.NewEventQueue()
+	}
+	lg.initMissingStructures()
+	lg.Player.FOV = rl.NewFOV(visionRange(lg.Player.P, TreeRange))
+	lg.PR = paths.NewPathRange(gruid.NewRange(0, 0, DungeonWidth, DungeonHeight))
+	lg.PRauto = paths.NewPathRange(gruid.NewRange(0, 0, DungeonWidth, DungeonHeight))
+	lg.rand = rand.New(&lg.RandState)
+	return lg, nil
+}
+
+func toJSONValue(v reflect.Value) (interface{}, error) {
+	switch v.Type() {
+	case pointType:
+		p := v.Interface().(gruid.Point)
+		return []int{p.X, p.Y}, nil
+	case cellType:
+		return cellName(v.Interface().(cell)), nil
+	case dungeonType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return dungeonToJSON(v.Interface().(*dungeon)), nil
+	case eventQueueType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return eventsToJSON(v.Interface().(*rl.EventQueue))
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toJSONValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		fallthrough
+	case reflect.Array:
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			x, err := toJSONValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			a[i] = x
+		}
+		return a, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		keys := v.MapKeys()
+		sortMapKeys(keys)
+		o := make(jsonObject, 0, len(keys))
+		for _, k := range keys {
+			x, err := toJSONValue(v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonField{mapKeyString(k), x})
+		}
+		return o, nil
+	case reflect.Struct:
+		t := v.Type()
+		o := jsonObject{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Type == fovType || f.Type == pathRangeType {
+				continue
+			}
+			x, err := toJSONValue(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			}
+			o = append(o, jsonField{f.Name, x})
+		}
+		return o, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %v", v.Type())
+}
+
+func sortMapKeys(keys []reflect.Value) {
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		switch {
+		case ki.Type() == pointType:
+			return idx(ki.Interface().(gruid.Point)) < idx(kj.Interface().(gruid.Point))
+		case ki.Kind() == reflect.String:
+			return ki.String() < kj.String()
+		default:
+			return ki.Int() < kj.Int()
+		}
+	})
+}
+
+func mapKeyString(k reflect.Value) string {
+	switch {
+	case k.Type() == pointType:
+		p := k.Interface().(gruid.Point)
+		return fmt.Sprintf("%d,%d", p.X, p.Y)
+	case k.Kind() == reflect.String:
+		return k.String()
+	default:
+		return strconv.FormatInt(k.Int(), 10)
+	}
+}
+
+func dungeonToJSON(d *dungeon) [][]string {
+	max := d.Grid.Size()
+	rows := make([][]string, max.Y)
+	for y := range rows {
+		rows[y] = make([]string, max.X)
+		for x := range rows[y] {
+			rows[y][x] = cellName(d.Cell(gruid.Point{x, y}))
+		}
+	}
+	return rows
+}
+
+func eventsToJSON(eq *rl.EventQueue) (interface{}, error) {
+	// pop events from a copy of the queue, to get them in order
+	data, err := eq.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	cp := &rl.EventQueue{}
+	if err := cp.GobDecode(data); err != nil {
+		return nil, err
+	}
+	evs := []interface{}{}
+	for !cp.Empty() {
+		ev, rank := cp.PopR()
+		x, err := toJSONValue(reflect.ValueOf(ev))
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, jsonObject{
+			{"Rank", rank},
+			{"Type", eventType(ev)},
+			{"Event", x},
+		})
+	}
+	return evs, nil
+}
+
+func fromJSONValue(x interface{}, v reflect.Value, path string) error {
+	errorf := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%s: %s", path, fmt.Sprintf(format, a...))
+	}
+	switch v.Type() {
+	case pointType:
+		a, ok := x.([]interface{})
+		if !ok || len(a) != 2 {
+			return errorf("expected [x, y] position")
+		}
+		p := gruid.Point{}
+		if err := fromJSONValue(a[0], reflect.ValueOf(&p.X).Elem(), path); err != nil {
+			return err
+		}
+		if err := fromJSONValue(a[1], reflect.ValueOf(&p.Y).Elem(), path); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(p))
+		return nil
+	case cellType:
+		s, ok := x.(string)
+		if !ok {
+			return errorf("expected cell name")
+		}
+		c, err := parseCellName(s)
+		if err != nil {
+			return errorf("%v", err)
+		}
+		v.Set(reflect.ValueOf(c))
+		return nil
+	case dungeonType:
+		if x == nil {
+			return nil
+		}
+		d, err := dungeonFromJSON(x, path)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(d))
+		return nil
+	case eventQueueType:
+		if x == nil {
+			return nil
+		}
+		eq, err := eventsFromJSON(x, path)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(eq))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := x.(bool)
+		if !ok {
+			return errorf("expected boolean")
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := x.(string)
+		if !ok {
+			return errorf("expected string")
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := x.(json.Number)
+		if !ok {
+			return errorf("expected number")
+		}
+		i, err := strconv.ParseInt(string(n), 10, v.Type().Bits())
+		if err != nil {
+			return errorf("%v", err)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := x.(json.Number)
+		if !ok {
+			return errorf("expected number")
+		}
+		i, err := strconv.ParseUint(string(n), 10, v.Type().Bits())
+		if err != nil {
+			return errorf("%v", err)
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		n, ok := x.(json.Number)
+		if !ok {
+			return errorf("expected number")
+		}
+		f, err := strconv.ParseFloat(string(n), v.Type().Bits())
+		if err != nil {
+			return errorf("%v", err)
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		pv := reflect.New(v.Type().Elem())
+		if err := fromJSONValue(x, pv.Elem(), path); err != nil {
+			return err
+		}
+		v.Set(pv)
+	case reflect.Slice:
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		a, ok := x.([]interface{})
+		if !ok {
+			return errorf("expected array")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(a), len(a))
+		for i, y := range a {
+			if err := fromJSONValue(y, sv.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	case reflect.Array:
+		a, ok := x.([]interface{})
+		if !ok || len(a) != v.Len() {
+			return errorf("expected array of length %d", v.Len())
+		}
+		for i, y := range a {
+			if err := fromJSONValue(y, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		o, ok := x.(map[string]interface{})
+		if !ok {
+			return errorf("expected object")
+		}
+		mv := reflect.MakeMapWithSize(v.Type(), len(o))
+		for ks, y := range o {
+			kpath := fmt.Sprintf("%s[%s]", path, ks)
+			k, err := parseMapKey(ks, v.Type().Key())
+			if err != nil {
+				return fmt.Errorf("%s: %v", kpath, err)
+			}
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := fromJSONValue(y, ev, kpath); err != nil {
+				return err
+			}
+			mv.SetMapIndex(k, ev)
+		}
+		v.Set(mv)
+	case reflect.Struct:
+		o, ok := x.(map[string]interface{})
+		if !ok {
+			return errorf("expected object")
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			y, ok := o[f.Name]
+			if !ok {
+				continue
+			}
+			if err := fromJSONValue(y, v.Field(i), path+"."+f.Name); err != nil {
+				return err
+			}
+		}
+		for k := range o {
+			if _, ok := t.FieldByName(k); !ok {
+				return errorf("unknown field %s", k)
+			}
+		}
+	default:
+		return errorf("unsupported type: %v", v.Type())
+	}
+	return nil
+}
+
+func parseMapKey(s string, t reflect.Type) (reflect.Value, error) {
+	k := reflect.New(t).Elem()
+	switch {
+	case t == pointType:
+		p := gruid.Point{}
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		if err != nil {
+			return k, fmt.Errorf("bad position key: %q", s)
+		}
+		k.Set(reflect.ValueOf(p))
+	case t.Kind() == reflect.String:
+		k.SetString(s)
+	default:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return k, fmt.Errorf("bad key: %v", err)
+		}
+		k.SetInt(i)
+	}
+	return k, nil
+}
+
+func dungeonFromJSON(x interface{}, path string) (*dungeon, error) {
+	rows, ok := x.([]interface{})
+	if !ok || len(rows) != DungeonHeight {
+		return nil, fmt.Errorf("%s: expected %d rows", path, DungeonHeight)
+	}
+	d := &dungeon{Grid: rl.NewGrid(DungeonWidth, DungeonHeight)}
+	for y, row := range rows {
+		cells, ok := row.([]interface{})
+		if !ok || len(cells) != DungeonWidth {
+			return nil, fmt.Errorf("%s[%d]: expected %d cells", path, y, DungeonWidth)
+		}
+		for x, c := range cells {
+			p := gruid.Point{x, y}
+			cv := reflect.New(cellType).Elem()
+			if err := fromJSONValue(c, cv, fmt.Sprintf("%s[%d][%d]", path, y, x)); err != nil {
+				return nil, err
+			}
+			d.Grid.Set(p, rl.Cell(cv.Int()))
+		}
+	}
+	return d, nil
+}
+
+func eventsFromJSON(x interface{}, path string) (*rl.EventQueue, error) {
+	evs, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected array of events", path)
+	}
+	types := map[string]reflect.Type{}
+	for _, ev := range eventTypes {
+		types[eventType(ev)] = reflect.TypeOf(ev)
+	}
+	eq := rl.NewEventQueue()
+	for i, y := range evs {
+		epath := fmt.Sprintf("%s[%d]", path, i)
+		o, ok := y.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected event object", epath)
+		}
+		var rank int
+		if err := fromJSONValue(o["Rank"], reflect.ValueOf(&rank).Elem(), epath+".Rank"); err != nil {
+			return nil, err
+		}
+		name, _ := o["Type"].(string)
+		t, ok := types[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown event type: %q", epath, name)
+		}
+		ev := reflect.New(t).Elem()
+		if err := fromJSONValue(o["Event"], ev, epath+".Event"); err != nil {
+			return nil, err
+		}
+		eq.Push(ev.Interface(), rank)
+	}
+	return eq, nil
+}
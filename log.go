@@ -106,15 +106,15 @@ func (g *game) PrintEntry(e logEntry) {
 }
 
 func (g *game) StoryPrint(s string) {
-	g.Stats.Story = append(g.Stats.Story, fmt.Sprintf("Depth %2d|Turn %5d| %s", g.Depth, g.Turn, s))
+	g.StoryEntry(timelineEntry{Text: s})
 }
 
 func (g *game) StoryPrintf(format string, a ...interface{}) {
-	g.Stats.Story = append(g.Stats.Story, fmt.Sprintf("Depth %2d|Turn %5d| %s", g.Depth, g.Turn, fmt.Sprintf(format, a...)))
+	g.StoryEntry(timelineEntry{Text: fmt.Sprintf(format, a...)})
 }
 
 func (g *game) CrackSound() (text string) {
-	switch RandInt(4) {
+	switch g.randInt(4) {
 	case 0:
 		text = "Crack!"
 	case 1:
@@ -128,7 +128,7 @@ func (g *game) CrackSound() (text string) {
 }
 
 func (g *game) ExplosionSound() (text string) {
-	switch RandInt(3) {
+	switch g.randInt(3) {
 	case 0:
 		text = "Bang!"
 	case 1:
@@ -16,6 +16,8 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/anaseto/gruid"
 	"github.com/anaseto/gruid/paths"
 	"github.com/anaseto/gruid/rl"
@@ -307,20 +309,20 @@ func (g *game) SeeNotable(c cell, p gruid.Point) {
 		mag := g.Objects.Magaras[p]
 		dp := &mappingPath{g: g}
 		path := g.PR.AstarPath(dp, g.Player.P, p)
+		e := timelineEntry{Kind: TimelineItemFound, Name: mag.String(), Text: fmt.Sprintf("Spotted %s", mag)}
 		if len(path) > 0 {
-			g.StoryPrintf("Spotted %s (distance: %d)", mag, len(path))
-		} else {
-			g.StoryPrintf("Spotted %s", mag)
+			e.Text = fmt.Sprintf("Spotted %s (distance: %d)", mag, len(path))
 		}
+		g.StoryEntry(e)
 	case ItemCell:
 		it := g.Objects.Items[p]
 		dp := &mappingPath{g: g}
 		path := g.PR.AstarPath(dp, g.Player.P, p)
+		e := timelineEntry{Kind: TimelineItemFound, Name: it.ShortDesc(g), Text: fmt.Sprintf("Spotted %s", it.ShortDesc(g))}
 		if len(path) > 0 {
-			g.StoryPrintf("Spotted %s (distance: %d)", it.ShortDesc(g), len(path))
-		} else {
-			g.StoryPrintf("Spotted %s", it.ShortDesc(g))
+			e.Text = fmt.Sprintf("Spotted %s (distance: %d)", it.ShortDesc(g), len(path))
 		}
+		g.StoryEntry(e)
 	case StairCell:
 		st := g.Objects.Stairs[p]
 		dp := &mappingPath{g: g}
@@ -432,6 +434,14 @@ func (g *game) Ray(p gruid.Point) []gruid.Point {
 	return ps
 }
 
+// ComputeMapInfo updates noise and line of sight information after a player
+// move.
+func (g *game) ComputeMapInfo() {
+	g.ComputeNoise()
+	g.ComputeLOS()
+	g.ComputeMonsterLOS()
+}
+
 func (g *game) ComputeNoise() {
 	dij := &noisePath{g: g}
 	rg := DefaultLOSRange
@@ -450,7 +460,7 @@ func (g *game) ComputeNoise() {
 		}
 		mons := g.MonsterAt(n.P)
 		if mons.Exists() && mons.State != Resting && mons.State != Watching &&
-			(RandInt(rmax) > 0 || terrain(g.Dungeon.Cell(mons.P)) == QueenRockCell) {
+			(g.randInt(rmax) > 0 || terrain(g.Dungeon.Cell(mons.P)) == QueenRockCell) {
 			switch mons.Kind {
 			case MonsMirrorSpecter, MonsSatowalgaPlant, MonsButterfly:
 				if mons.Kind == MonsMirrorSpecter && g.Player.Inventory.Body == CloakHear {
@@ -504,6 +514,19 @@ func (m *monster) SeesLight(g *game, p gruid.Point) bool {
 	return true
 }
 
+// SeesAround reports whether the monster can see a position outside its view
+// cone: spiders see all around them, and haze cats too, in the dark of
+// blackout levels.
+func (m *monster) SeesAround(g *game, p gruid.Point) bool {
+	switch m.Kind {
+	case MonsSpider:
+		return true
+	case MonsHazeCat:
+		return g.Params.Event[g.Depth] == BlackoutLevel && !g.Illuminated(p)
+	}
+	return false
+}
+
 func (m *monster) Sees(g *game, p gruid.Point) bool {
 	var darkRange = 4
 	if m.Kind == MonsHazeCat {
@@ -516,7 +539,7 @@ func (m *monster) Sees(g *game, p gruid.Point) bool {
 		darkRange = 1
 	}
 	const tableRange = 1
-	if !(m.LOS[p] && (inViewCone(m.Dir, m.P, p) || m.Kind == MonsSpider)) {
+	if !(m.LOS[p] && (inViewCone(m.Dir, m.P, p) || m.SeesAround(g, p))) {
 		return false
 	}
 	if m.State == Resting && distance(m.P, p) > 1 {
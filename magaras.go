@@ -86,7 +86,7 @@ func (g *game) RandomStartingMagara() magara {
 	var mag magaraKind
 loop:
 	for {
-		mag = mags[RandInt(len(mags))]
+		mag = mags[g.randInt(len(mags))]
 		for _, m := range g.GeneratedMagaras {
 			if m == mag {
 				continue loop
@@ -107,7 +107,7 @@ func (g *game) RandomMagara() magara {
 	var mag magaraKind
 loop:
 	for {
-		mag = mags[RandInt(len(mags))]
+		mag = mags[g.randInt(len(mags))]
 		for _, m := range g.GeneratedMagaras {
 			if m == mag {
 				continue loop
@@ -192,52 +192,25 @@ func (g *game) UseMagara(n int) (err error) {
 		return err
 	}
 	g.Stats.MagarasUsed++
+	ConductNoMagaras.Break(g)
 	g.Stats.UsedMagaras[mag.Kind]++
 	g.Stats.DMagaraUses[g.Depth]++
 	g.Player.MP -= mag.MPCost(g)
 	g.Player.Magaras[n].Charges--
-	g.StoryPrintf("Evoked %s (MP: %d, Charges: %d)", mag, g.Player.MP, g.Player.Magaras[n].Charges)
+	g.StoryEntry(timelineEntry{Kind: TimelineMagaraUse, Name: mag.String(),
+		Text: fmt.Sprintf("Evoked %s (MP: %d, Charges: %d)", mag, g.Player.MP, g.Player.Magaras[n].Charges)})
 	if mag.Harmonic() {
 		g.Stats.HarmonicMagUse++
-		if g.Stats.HarmonicMagUse == 6 {
-			AchHarmonistNovice.Get(g)
-		}
-		if g.Stats.HarmonicMagUse == 11 {
-			AchHarmonistInitiate.Get(g)
-		}
-		if g.Stats.HarmonicMagUse == 16 {
-			AchHarmonistMaster.Get(g)
-		}
 	} else if mag.Oric() {
 		g.Stats.OricMagUse++
-		if g.Stats.OricMagUse == 6 {
-			AchNoviceOricCelmist.Get(g)
-		}
-		if g.Stats.OricMagUse == 11 {
-			AchInitiateOricCelmist.Get(g)
-		}
-		if g.Stats.OricMagUse == 16 {
-			AchMasterOricCelmist.Get(g)
-		}
 	} else if mag.Kind == FireMagara {
 		g.Stats.FireUse++
-		if g.Stats.FireUse == 2 {
-			AchPyromancerNovice.Get(g)
-		}
-		if g.Stats.FireUse == 4 {
-			AchPyromancerInitiate.Get(g)
-		}
-		if g.Stats.FireUse == 6 {
-			AchPyromancerMaster.Get(g)
-		}
 	}
 	switch mag.Kind {
 	case TeleportMagara, TeleportOtherMagara, BlinkMagara, SwappingMagara, DispersalMagara:
 		g.Stats.OricTelUse++
-		if g.Stats.OricTelUse == 14 {
-			AchTeleport.Get(g)
-		}
 	}
+	g.CheckAchievements(CounterHarmonicMagUse, CounterOricMagUse, CounterFireUse, CounterOricTelUse)
 	return nil
 }
 
@@ -442,9 +415,10 @@ func (g *game) BlinkPos(mpassable bool) gruid.Point {
 	if len(losPos) == 0 {
 		return invalidPos
 	}
-	q := losPos[RandInt(len(losPos))]
+	sortPositions(losPos)
+	q := losPos[g.randInt(len(losPos))]
 	for i := 0; i < 4; i++ {
-		p := losPos[RandInt(len(losPos))]
+		p := losPos[g.randInt(len(losPos))]
 		if distance(q, g.Player.P) < distance(p, g.Player.P) {
 			q = p
 		}
@@ -478,7 +452,7 @@ func (g *game) MonstersInLOS() []*monster {
 	}
 	// shuffle before, because the order could be unnaturally predicted
 	for i := 0; i < len(ms); i++ {
-		j := i + RandInt(len(ms)-i)
+		j := i + g.randInt(len(ms)-i)
 		ms[i], ms[j] = ms[j], ms[i]
 	}
 	return ms
@@ -493,7 +467,7 @@ func (g *game) MonstersInCardinalLOS() []*monster {
 	}
 	// shuffle before, because the order could be unnaturally predicted
 	for i := 0; i < len(ms); i++ {
-		j := i + RandInt(len(ms)-i)
+		j := i + g.randInt(len(ms)-i)
 		ms[i], ms[j] = ms[j], ms[i]
 	}
 	return ms
@@ -626,7 +600,7 @@ func (g *game) Fog(at gruid.Point, radius int) {
 		_, ok := g.Clouds[n.P]
 		if !ok && g.Dungeon.Cell(n.P).AllowsFog() {
 			g.Clouds[n.P] = CloudFog
-			g.PushEvent(&posEvent{P: n.P, Action: CloudEnd}, g.Turn+DurationFog+RandInt(DurationFog/2))
+			g.PushEvent(&posEvent{P: n.P, Action: CloudEnd}, g.Turn+DurationFog+g.randInt(DurationFog/2))
 		}
 	}
 	g.ComputeLOS()
@@ -683,7 +657,7 @@ func (g *game) EvokeSleeping() error {
 		}
 		mons.State = Resting
 		mons.Dir = ZP
-		mons.ExhaustTime(g, 4+RandInt(2))
+		mons.ExhaustTime(g, 4+g.randInt(2))
 		targets = append(targets, g.Ray(mons.P)...)
 	}
 	if len(targets) == 0 {
@@ -861,7 +835,7 @@ func (g *game) CreateMagicalBarrierAt(p gruid.Point) {
 	g.Dungeon.SetCell(p, BarrierCell)
 	delete(g.Clouds, p)
 	g.MagicalBarriers[p] = t
-	g.PushEvent(&posEvent{P: p, Action: ObstructionEnd}, g.Turn+DurationMagicalBarrier+RandInt(DurationMagicalBarrier/2))
+	g.PushEvent(&posEvent{P: p, Action: ObstructionEnd}, g.Turn+DurationMagicalBarrier+g.randInt(DurationMagicalBarrier/2))
 }
 
 func (g *game) EvokeEnergyMagara() error {
@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -13,17 +14,31 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/anaseto/gruid"
-	"github.com/anaseto/gruid/ui"
 )
 
 func main() {
 	optVersion := flag.Bool("v", false, "print version number")
 	optNoAnim := flag.Bool("n", false, "no animations")
 	optReplay := flag.String("r", "", "path to replay file (_ means default location)")
+	optExportCast := flag.String("export-cast", "", "export replay (given by -r, or last one) to asciinema cast `file` and exit")
+	optExportGIF := flag.String("export-gif", "", "render replay (given by -r, or last one) with tiles to animated GIF `file`, or PNG files if it ends with .png, and exit")
+	optGIFFPS := flag.Int("gif-fps", 0, "maximum frames per second for -export-gif (0 means no limit)")
+	optGIFCrop := flag.String("gif-crop", "", "render only cells in `x,y,w,h` range for -export-gif")
+	optVerifyReplay := flag.String("verify-replay", "", "re-simulate input replay `file` (_ means default location), report divergences and exit")
 	optLogFile := flag.String("o", "", "log to output file")
+	optSeed := flag.Int64("seed", 0, "random seed for a new game (0 means time based)")
+	optExportSave := flag.String("export-save", "", "export saved game to JSON file and exit")
+	optImportSave := flag.String("import-save", "", "import saved game from JSON file and exit")
+	optBots := flag.Int("bots", 0, "play `N` headless games with an agent, report results and exit")
+	optAgent := flag.String("agent", "explorer", "agent used by -bots")
+	optGenStats := flag.Int("genstats", 0, "generate `N` full dungeons, report level statistics and exit")
+	optGenStatsCSV := flag.String("genstats-csv", "", "write -genstats statistics in CSV form to `file`")
+	optLintRooms := flag.Bool("lint-rooms", false, "check room templates, print their previews and exit")
 	opt16colors := new(bool)
 	opt256colors := new(bool)
 	optFullscreen := new(bool)
@@ -39,6 +54,68 @@ func main() {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
+	if tpls, err := LoadRoomTemplates(); err != nil {
+		if *optLintRooms {
+			log.Fatalf("loading room templates: %v", err)
+		}
+		log.Printf("loading room templates: %v", err)
+	} else {
+		if !*optLintRooms {
+			// -lint-rooms reports the problems of every template
+			tpls, err = CheckRoomTemplates(tpls)
+			if err != nil {
+				log.Printf("loading room templates: %v", err)
+			}
+		}
+		AddRoomTemplates(tpls)
+	}
+	if *optLintRooms {
+		if err := LintRooms(os.Stdout); err != nil {
+			log.Fatalf("linting rooms: %v", err)
+		}
+		os.Exit(0)
+	}
+	if *optExportSave != "" {
+		if err := ExportSave(*optExportSave); err != nil {
+			log.Fatalf("exporting saved game: %v", err)
+		}
+		os.Exit(0)
+	}
+	if *optImportSave != "" {
+		if err := ImportSave(*optImportSave); err != nil {
+			log.Fatalf("importing saved game: %v", err)
+		}
+		os.Exit(0)
+	}
+	if *optVerifyReplay != "" {
+		err := VerifyInputReplay(replayPath(*optVerifyReplay, "inputreplay"), os.Stdout)
+		if err != nil {
+			log.Fatalf("verifying replay: %v", err)
+		}
+		os.Exit(0)
+	}
+	if *optBots > 0 {
+		seed := *optSeed
+		if seed == 0 {
+			seed = 1
+		}
+		r, err := playBots(*optAgent, *optBots, seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r.Write(os.Stdout)
+		os.Exit(0)
+	}
+	if *optGenStats > 0 {
+		seed := *optSeed
+		if seed == 0 {
+			seed = 1
+		}
+		if err := GenStats(*optGenStats, seed, *optGenStatsCSV); err != nil {
+			log.Fatalf("generation statistics: %v", err)
+		}
+		os.Exit(0)
+	}
 	if *optNoAnim {
 		DisableAnimations = true
 	}
@@ -60,32 +137,76 @@ func main() {
 		log.Print(err)
 	}
 	applyThemeConf()
+	if *optExportCast != "" {
+		replay := *optReplay
+		if replay == "" {
+			replay = "_"
+		}
+		if err := ExportCast(replayPath(replay, "replay"), *optExportCast); err != nil {
+			log.Fatalf("exporting cast: %v", err)
+		}
+		os.Exit(0)
+	}
+	if *optExportGIF != "" {
+		replay := *optReplay
+		if replay == "" {
+			replay = "_"
+		}
+		opts := imageExportOptions{FPS: *optGIFFPS}
+		if *optGIFCrop != "" {
+			rg, err := parseCrop(*optGIFCrop)
+			if err != nil {
+				log.Fatal(err)
+			}
+			opts.Crop = rg
+		}
+		if err := ExportImages(replayPath(replay, "replay"), *optExportGIF, opts); err != nil {
+			log.Fatalf("exporting images: %v", err)
+		}
+		os.Exit(0)
+	}
 	initDriver(*optFullscreen)
 	if *optReplay != "" {
 		RunReplay(*optReplay)
 	} else {
-		RunGame(*optLogFile)
+		RunGame(*optLogFile, *optSeed)
 	}
 }
 
-func RunGame(logfile string) {
+func RunGame(logfile string, seed int64) {
 	gd := gruid.NewGrid(UIWidth, UIHeight)
-	m := &model{gd: gd, g: &game{}}
-	var repw io.WriteCloser
+	m := &model{gd: gd, g: &game{Seed: seed}}
+	var repw, indexw, inputw io.WriteCloser
 	dir, err := DataDir()
 	defer func() {
 		if repw != nil {
 			repw.Close()
 		}
+		if indexw != nil {
+			indexw.Close()
+		}
+		if inputw != nil {
+			inputw.Close()
+		}
 		if m.finished && dir != "" {
 			RemoveSaveFile()
-			_, err := os.Stat(filepath.Join(dir, "replay.part"))
-			if err != nil {
-				log.Printf("no replay file: %v", err)
-				return
+			for _, files := range [][2]string{
+				{"replay.part", "replay"},
+				{"replay.part.index", "replay.index"},
+				{"inputreplay.part", "inputreplay"},
+			} {
+				part, file := files[0], files[1]
+				_, err := os.Stat(filepath.Join(dir, part))
+				if err != nil {
+					log.Printf("no replay file: %v", err)
+					continue
+				}
+				if err := os.Rename(filepath.Join(dir, part), filepath.Join(dir, file)); err != nil {
+					log.Printf("writing replay file: %v", err)
+				}
 			}
-			if err := os.Rename(filepath.Join(dir, "replay.part"), filepath.Join(dir, "replay")); err != nil {
-				log.Printf("writing replay file: %v", err)
+			if err := ArchiveGame(time.Now()); err != nil {
+				log.Printf("archiving game: %v", err)
 			}
 		}
 	}()
@@ -96,6 +217,18 @@ func RunGame(logfile string) {
 		} else {
 			log.Printf("writing to replay file: %v", err)
 		}
+		index, err := os.OpenFile(filepath.Join(dir, "replay.part.index"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err == nil {
+			indexw = index
+		} else {
+			log.Printf("writing to replay index file: %v", err)
+		}
+		inputs, err := os.OpenFile(filepath.Join(dir, "inputreplay.part"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err == nil {
+			inputw = inputs
+		} else {
+			log.Printf("writing to input replay file: %v", err)
+		}
 	} else {
 		log.Print(err)
 	}
@@ -114,9 +247,16 @@ func RunGame(logfile string) {
 	if !Tiles && !LogGame {
 		log.SetOutput(ioutil.Discard)
 	}
+	var model gruid.Model = m
+	if inputw != nil {
+		model = newInputRecorder(m, inputw)
+	}
+	if indexw != nil {
+		model = newReplayIndexer(model, m, indexw)
+	}
 	app := gruid.NewApp(gruid.AppConfig{
 		Driver:      driver,
-		Model:       m,
+		Model:       model,
 		FrameWriter: repw,
 	})
 	err = app.Start(context.Background())
@@ -128,14 +268,77 @@ func RunGame(logfile string) {
 	}
 }
 
+// ExportSave writes the JSON form of the saved game to a file.
+func ExportSave(file string) error {
+	g := &game{}
+	load, err := g.Load()
+	if err != nil {
+		return err
+	}
+	if !load {
+		return errors.New("no saved game")
+	}
+	data, err := g.GameSaveJSON()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// ImportSave replaces the saved game with the game in a JSON file.
+func ImportSave(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	g := &game{}
+	lg, err := g.DecodeGameSaveJSON(data)
+	if err != nil {
+		return err
+	}
+	return lg.Save()
+}
+
+// GenStats generates n full dungeons and writes their level statistics to
+// the standard output, and in CSV form to csvfile if not empty.
+func GenStats(n int, seed int64, csvfile string) error {
+	gs := generateStats(n, seed)
+	if err := gs.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+	if csvfile == "" {
+		return nil
+	}
+	f, err := os.Create(csvfile)
+	if err != nil {
+		return err
+	}
+	if err := gs.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replayPath returns the path of a replay file given on the command line: _
+// means the file with the default name in the data directory.
+func replayPath(file, name string) string {
+	if file != "_" {
+		return file
+	}
+	dir, err := DataDir()
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	return filepath.Join(dir, name)
+}
+
 func RunReplay(file string) {
-	if file == "_" {
-		dir, err := DataDir()
-		if err == nil {
-			file = filepath.Join(dir, "replay")
-		} else {
-			log.Print(err)
-		}
+	file = replayPath(file, "replay")
+	if IsInputReplay(file) {
+		RunInputReplay(file)
+		return
 	}
 	replay, err := os.Open(file)
 	if err != nil {
@@ -144,16 +347,105 @@ func RunReplay(file string) {
 	defer replay.Close()
 	fd, err := gruid.NewFrameDecoder(replay)
 	if err != nil {
-		log.Printf("frame decoder: %v", err)
+		log.Fatalf("frame decoder: %v", err)
 	}
-	gd := gruid.NewGrid(UIWidth, UIHeight)
-	rep := ui.NewReplay(ui.ReplayConfig{
-		Grid:         gd,
-		FrameDecoder: fd,
+	times, err := replayFrameTimes(fd)
+	if err != nil {
+		log.Fatalf("loading replay file: %v", err)
+	}
+	if _, err := replay.Seek(0, io.SeekStart); err != nil {
+		log.Fatalf("loading replay file: %v", err)
+	}
+	fd, err = gruid.NewFrameDecoder(replay)
+	if err != nil {
+		log.Fatalf("frame decoder: %v", err)
+	}
+	marks, err := LoadReplayIndex(file)
+	if err != nil {
+		log.Printf("loading replay index: %v", err)
+	}
+	app := gruid.NewApp(gruid.AppConfig{
+		Driver: driver,
+		Model:  newReplayViewer(fd, times, marks),
 	})
+	if err := app.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ExportCast converts a replay file into an asciinema cast file.
+func ExportCast(replay, file string) error {
+	if IsInputReplay(replay) {
+		return errors.New("input replays cannot be exported: use a frame replay")
+	}
+	r, err := os.Open(replay)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	fd, err := gruid.NewFrameDecoder(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Harmonist %s replay", Version)
+	if err := WriteCast(fd, f, title); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ExportImages renders a replay file with tiles into an animated GIF file or,
+// if file has a .png extension, into a sequence of numbered PNG files.
+func ExportImages(replay, file string, opts imageExportOptions) error {
+	if IsInputReplay(replay) {
+		return errors.New("input replays cannot be exported: use a frame replay")
+	}
+	r, err := os.Open(replay)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	fd, err := gruid.NewFrameDecoder(r)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(file, ".png") {
+		n, err := WritePNGs(fd, strings.TrimSuffix(file, ".png"), opts)
+		if err == nil {
+			fmt.Printf("Wrote %d PNG files.\n", n)
+		}
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := WriteGIF(fd, f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RunInputReplay shows the re-simulation of an input replay.
+func RunInputReplay(file string) {
+	entries, err := LoadInputReplay(file)
+	if err != nil {
+		log.Fatalf("loading replay file: %v", err)
+	}
+	rs, err := newResim(entries)
+	if err != nil {
+		log.Fatalf("re-simulating replay: %v", err)
+	}
+	defer rs.Close()
 	app := gruid.NewApp(gruid.AppConfig{
 		Driver: driver,
-		Model:  rep,
+		Model:  &inputReplayViewer{rs: rs, gd: gruid.NewGrid(UIWidth, UIHeight), speed: 1},
 	})
 	if err := app.Start(context.Background()); err != nil {
 		log.Fatal(err)
@@ -51,6 +51,12 @@ type pagerMode int
 const (
 	modeLogs pagerMode = iota
 	modeHelpKeys
+	modeDailyLedger
+	modeMorgueDump
+	modeCareer
+	modeHighScores
+	modeConducts
+	modeAchievements
 )
 
 type menuMode int
@@ -64,6 +70,7 @@ const (
 	modeEvocation
 	modeEquip
 	modeWizard
+	modeMorgue
 )
 
 type model struct {
@@ -94,6 +101,13 @@ type model struct {
 	critical    bool
 	auto        bool
 	confirm     bool
+	newGame     bool             // game was not loaded from a save
+	daily       bool             // start the daily challenge (js main menu)
+	morgue      []morgueEntry    // archived games in the history screen
+	dumpView    dumpView         // game dump shown in the pager
+	careerPages []careerPage     // career statistics screen pages
+	careerPage  int              // current career statistics page
+	clock       func() time.Time // current time, if not time.Now (replays)
 }
 
 type mapTargInfo struct {
@@ -269,17 +283,31 @@ func (md *model) init() gruid.Effect {
 	if !load {
 		g.InitLevel()
 		g.checks()
+		md.newGame = true
 	} else {
-		g.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		g.rand = rand.New(&g.RandState)
 	}
 	if err != nil {
-		g.PrintStyled("Warning: could not load old saved game… starting new game.", logError)
+		if load {
+			g.PrintfStyled("Warning: %v… restored previous save.", logError, err)
+		} else {
+			g.PrintfStyled("Warning: could not load saved game (%v)… starting new game.", logError, err)
+		}
 		log.Printf("Error: %v", err)
 	}
+	if md.daily {
+		md.daily = false
+		if !md.newGame {
+			md.g.PrintStyled("You have to finish your current game before starting the daily challenge.", logError)
+		} else if err := md.startDaily(); err != nil {
+			md.g.PrintStyled(err.Error(), logError)
+		}
+	}
 
-	md.g.ComputeNoise()
-	md.g.ComputeLOS()
-	md.g.ComputeMonsterLOS()
+	g = md.g
+	g.ComputeNoise()
+	g.ComputeLOS()
+	g.ComputeMonsterLOS()
 	md.updateStatusInfo()
 	md.targ.ex = &examination{}
 	md.CancelExamine()
@@ -290,6 +318,14 @@ func (md *model) init() gruid.Effect {
 	return gruid.Sub(subSig)
 }
 
+// now returns the current time, as seen by the game.
+func (md *model) now() time.Time {
+	if md.clock != nil {
+		return md.clock()
+	}
+	return time.Now()
+}
+
 func (md *model) more(msg gruid.Msg) bool {
 	switch msg := msg.(type) {
 	case gruid.MsgKeyDown:
@@ -356,6 +392,12 @@ func (md *model) update(msg gruid.Msg) gruid.Effect {
 		switch msg := msg.(type) {
 		case gruid.MsgKeyDown:
 			md.mode = modeNormal
+			if md.newGame && (msg.Key == "d" || msg.Key == "D") {
+				md.newGame = false
+				if err := md.startDaily(); err != nil {
+					md.g.PrintStyled(err.Error(), logError)
+				}
+			}
 		case gruid.MsgMouse:
 			if msg.Action != gruid.MouseMove {
 				md.mode = modeNormal
@@ -412,6 +454,8 @@ func (md *model) update(msg gruid.Msg) gruid.Effect {
 			eff = md.updateKeysMenu(msg)
 		case modeKeysChange:
 			eff = md.updateKeysChange(msg)
+		case modeMorgue:
+			eff = md.updateMorgueMenu(msg)
 		default:
 			eff = md.updateMenu(msg)
 		}
@@ -694,9 +738,7 @@ func (md *model) EndTurn() gruid.Effect {
 }
 
 func (md *model) updateMapInfo() {
-	md.g.ComputeNoise()
-	md.g.ComputeLOS()
-	md.g.ComputeMonsterLOS()
+	md.g.ComputeMapInfo()
 	md.updateStatusInfo()
 	if md.g.Highlight != nil {
 		md.examine(md.targ.ex.p)
@@ -705,8 +747,19 @@ func (md *model) updateMapInfo() {
 
 func (md *model) updatePager(msg gruid.Msg) gruid.Effect {
 	md.pager.Update(msg)
-	if md.pager.Action() == ui.PagerQuit {
+	switch md.pager.Action() {
+	case ui.PagerQuit:
 		md.mode = modeNormal
+		if md.pagerMode == modeMorgueDump {
+			md.openMorgue()
+		}
+	case ui.PagerPass:
+		switch md.pagerMode {
+		case modeCareer:
+			md.updateCareerPage(msg)
+		case modeMorgueDump:
+			md.updateDumpFilter(msg)
+		}
 	}
 	return nil
 }
@@ -721,9 +774,12 @@ func (md *model) updateSmallPager(msg gruid.Msg) gruid.Effect {
 
 func (md *model) updateDump(msg gruid.Msg) gruid.Effect {
 	md.pager.Update(msg)
-	if md.pager.Action() == ui.PagerQuit {
+	switch md.pager.Action() {
+	case ui.PagerQuit:
 		md.mode = modeQuit
 		return gruid.End()
+	case ui.PagerPass:
+		md.updateDumpFilter(msg)
 	}
 	return nil
 }
@@ -831,7 +887,7 @@ func (md *model) updateMenu(msg gruid.Msg) gruid.Effect {
 			if act != ui.MenuInvoke {
 				break
 			}
-			_, eff, err := md.normalModeAction(menuActions[md.menu.Active()])
+			_, eff, err := md.normalModeAction(menuActions()[md.menu.Active()])
 			if err != nil {
 				// should not happen
 				md.g.Printf("%v", err)
@@ -890,12 +946,11 @@ func (md *model) normalModeKeyDown(key gruid.Key, shift bool) (again bool, eff g
 
 func (md *model) death() {
 	g := md.g
-	g.LevelStats()
-	if len(g.Stats.Achievements) == 0 {
-		NoAchievement.Get(g)
-	}
-	g.PrintStyled("You die...", logSpecial)
+	g.Die()
+	md.recordHighScore()
 	g.PrintStyled("[(x) to continue]", logConfirm)
+	md.recordDaily()
+	md.recordCareer()
 	md.mode = modeEnd
 }
 
@@ -903,22 +958,37 @@ func (md *model) win() {
 	g := md.g
 	if g.Wizard {
 		g.PrintStyled("You escape by the magic portal! **WIZARD**", logSpecial)
-		g.PrintStyled("[(x) to continue]", logConfirm)
 	} else {
 		g.PrintStyled("You escape by the magic portal!", logSpecial)
-		g.PrintStyled("[(x) to continue]", logConfirm)
 	}
+	md.recordHighScore()
+	g.PrintStyled("[(x) to continue]", logConfirm)
+	md.recordDaily()
+	md.recordCareer()
 	md.mode = modeEnd
 }
 
+func (md *model) recordDaily() {
+	err := md.g.RecordDaily()
+	if err != nil {
+		md.g.PrintfStyled("Error recording daily challenge: %v", logError, err)
+	}
+}
+
 func (md *model) dump(err error) {
 	s := md.g.SimplifedDump(err)
-	lines := strings.Split(s, "\n")
-	stts := []ui.StyledText{}
-	for _, l := range lines {
-		stts = append(stts, ui.Text(l))
-	}
-	md.pager.SetLines(stts)
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	lines = append(lines, "", "Timeline:")
+	if len(md.g.Stats.Timeline) > 0 {
+		lines = append(lines, strings.Split(md.g.DumpStory(), "\n")...)
+	}
+	md.dumpView = dumpView{
+		title:      "Game summary",
+		lines:      lines,
+		timeline:   md.g.Stats.Timeline,
+		filterable: true,
+	}
+	md.setDumpPager()
 	md.pager.SetCursor(gruid.Point{0, 0})
 	//log.Printf("%v", s)
 	//log.Printf("%v", stts)
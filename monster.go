@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/anaseto/gruid"
 )
@@ -355,7 +357,7 @@ var MonsDesc = []string{
 	//MonsMarevorHelith: "Marevor Helith is an ancient undead nakrus very fond of teleporting people away. He is a well-known expert in the field of magaras - items that many people simply call magical objects. His current research focus is monolith creation. Marevor, a repentant necromancer, is now searching for his old disciple Jaixel in the Underground to help him overcome the past.",
 	MonsButterfly: "Underground's butterflies, called kerejats, wander peacefully around, illuminating their surroundings.",
 	MonsCrazyImp:  "Crazy Imp is a crazy creature that likes to sing with its small guitar. It seems to be fond of monkeys and quite capable at finding them by smell. While singing it may attract unwanted attention.",
-	MonsHazeCat:   "Haze cats are a special variety of cats found in the Underground. They have very good night vision and are always alert.",
+	MonsHazeCat:   "Haze cats are a special variety of cats found in the Underground. They have very good night vision and are always alert. On blackout levels, they notice anything in the dark around them.",
 }
 
 type bandInfo struct {
@@ -485,6 +487,26 @@ var MonsBands = []monsterBandData{
 	UniqueCrazyImp:             {Monster: MonsCrazyImp},
 }
 
+func (mb monsterBand) String() string {
+	data := MonsBands[mb]
+	if !data.Band {
+		if mb >= SpecialLoneVampire && mb < UniqueCrazyImp {
+			return "special " + data.Monster.String()
+		}
+		return data.Monster.String()
+	}
+	kinds := []monsterKind{}
+	for mk := range data.Distribution {
+		kinds = append(kinds, mk)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	parts := []string{}
+	for _, mk := range kinds {
+		parts = append(parts, fmt.Sprintf("%s x%d", mk, data.Distribution[mk]))
+	}
+	return strings.Join(parts, ", ")
+}
+
 type monster struct {
 	Kind           monsterKind
 	Band           int
@@ -510,13 +532,13 @@ type monster struct {
 	Waiting        int
 }
 
-func (m *monster) Init() {
+func (m *monster) Init(g *game) {
 	m.Attack = m.Kind.BaseAttack()
 	m.P = invalidPos
 	m.LOS = make(map[gruid.Point]bool)
 	m.LastKnownPos = invalidPos
 	m.Search = invalidPos
-	if RandInt(2) == 0 {
+	if g.randInt(2) == 0 {
 		m.Left = true
 	}
 	switch m.Kind {
@@ -552,16 +574,16 @@ func (m *monster) Exists() bool {
 	return m != nil && !m.Dead
 }
 
-func (m *monster) Alternate() {
+func (m *monster) Alternate(g *game) {
 	if m.Left {
-		if RandInt(4) > 0 {
+		if g.randInt(4) > 0 {
 			m.Dir = leftDir(m.Dir)
 		} else {
 			m.Dir = rightDir(m.Dir)
 			m.Left = false
 		}
 	} else {
-		if RandInt(3) > 0 {
+		if g.randInt(3) > 0 {
 			m.Dir = rightDir(m.Dir)
 		} else {
 			m.Dir = leftDir(m.Dir)
@@ -755,7 +777,7 @@ func (m *monster) Explode(g *game) {
 			}
 		} else if g.Player.P == p {
 			m.InflictDamage(g, 1, 1)
-		} else if c.IsDestructible() && RandInt(3) > 0 {
+		} else if c.IsDestructible() && g.randInt(3) > 0 {
 			if c.IsDiggable() {
 				g.Dungeon.SetCell(p, RubbleCell)
 			} else {
@@ -802,7 +824,7 @@ func (m *monster) RandomFreeNeighbor(g *game) gruid.Point {
 	if len(fnb) == 0 {
 		return m.P
 	}
-	samedir := fnb[RandInt(len(fnb))]
+	samedir := fnb[g.randInt(len(fnb))]
 	for _, p := range fnb {
 		// invariant: pos != m.Pos
 		if inViewCone(m.Dir, m.P, m.P.Add(p)) {
@@ -810,10 +832,10 @@ func (m *monster) RandomFreeNeighbor(g *game) gruid.Point {
 			break
 		}
 	}
-	if RandInt(4) > 0 {
+	if g.randInt(4) > 0 {
 		return samedir
 	}
-	return fnb[RandInt(len(fnb))]
+	return fnb[g.randInt(len(fnb))]
 }
 
 type mbehaviour int
@@ -836,7 +858,7 @@ func (m *monster) SearchAround(g *game, p gruid.Point, radius int) gruid.Point {
 		SearchAroundCache = append(SearchAroundCache, n.P)
 	}
 	if len(SearchAroundCache) > 0 {
-		p := SearchAroundCache[RandInt(len(SearchAroundCache))]
+		p := SearchAroundCache[g.randInt(len(SearchAroundCache))]
 		return p
 	}
 	return invalidPos
@@ -847,13 +869,13 @@ func (m *monster) NextTarget(g *game) (p gruid.Point) {
 	p = m.P
 	switch band.Beh {
 	case BehWander:
-		if distance(m.P, band.Path[0]) < 8+RandInt(8) {
+		if distance(m.P, band.Path[0]) < 8+g.randInt(8) {
 			p = m.SearchAround(g, m.P, 4)
 			if p != invalidPos {
 				break
 			}
 		}
-		if m.Search != invalidPos && RandInt(2) == 0 {
+		if m.Search != invalidPos && g.randInt(2) == 0 {
 			p = m.SearchAround(g, m.Search, 7)
 			if p != invalidPos {
 				break
@@ -866,7 +888,7 @@ func (m *monster) NextTarget(g *game) (p gruid.Point) {
 		p = band.Path[0]
 	case BehExplore:
 		if m.Kind.CanOpenDoors() {
-			if m.Search != invalidPos && RandInt(4) == 0 {
+			if m.Search != invalidPos && g.randInt(4) == 0 {
 				p = m.SearchAround(g, m.Search, 7)
 			} else {
 				p = m.SearchAround(g, p, 5)
@@ -875,9 +897,9 @@ func (m *monster) NextTarget(g *game) (p gruid.Point) {
 				break
 			}
 		}
-		p = band.Path[RandInt(len(band.Path))]
+		p = band.Path[g.randInt(len(band.Path))]
 	case BehGuard:
-		if m.Search != invalidPos && distance(m.Search, m.P) < 5 && RandInt(2) == 0 {
+		if m.Search != invalidPos && distance(m.Search, m.P) < 5 && g.randInt(2) == 0 {
 			p = m.SearchAround(g, m.Search, 3)
 			if p != invalidPos {
 				break
@@ -885,7 +907,7 @@ func (m *monster) NextTarget(g *game) (p gruid.Point) {
 		}
 		p = band.Path[0]
 	case BehPatrol:
-		if m.Search != invalidPos && RandInt(4) > 0 {
+		if m.Search != invalidPos && g.randInt(4) > 0 {
 			p = m.SearchAround(g, m.Search, 7)
 			if p != m.P && p != invalidPos {
 				break
@@ -897,12 +919,12 @@ func (m *monster) NextTarget(g *game) (p gruid.Point) {
 			p = band.Path[0]
 		} else if distance(band.Path[0], m.P) < distance(band.Path[1], m.P) {
 			p = band.Path[0]
-			if RandInt(4) == 0 {
+			if g.randInt(4) == 0 {
 				p = band.Path[1]
 			}
 		} else {
 			p = band.Path[1]
-			if RandInt(4) == 0 {
+			if g.randInt(4) == 0 {
 				p = band.Path[0]
 			}
 		}
@@ -929,8 +951,8 @@ func (m *monster) HandleMonsSpecifics(g *game) (done bool) {
 				m.StartWatching()
 			}
 		default:
-			if RandInt(4) > 0 {
-				m.Alternate()
+			if g.randInt(4) > 0 {
+				m.Alternate(g)
 			}
 		}
 		// oklob plants are static ranged-only
@@ -945,7 +967,13 @@ func (m *monster) HandleMonsSpecifics(g *game) (done bool) {
 				break
 			}
 		}
-		for p, on := range g.Objects.Lights {
+		lights := []gruid.Point{}
+		for p := range g.Objects.Lights {
+			lights = append(lights, p)
+		}
+		sortPositions(lights)
+		for _, p := range lights {
+			on := g.Objects.Lights[p]
 			if !on && p == m.P {
 				g.Dungeon.SetCell(m.P, LightCell)
 				g.Objects.Lights[m.P] = true
@@ -960,7 +988,7 @@ func (m *monster) HandleMonsSpecifics(g *game) (done bool) {
 			}
 		}
 	case MonsCrazyImp:
-		if g.Player.Sees(m.P) && RandInt(2) == 0 && !m.Status(MonsConfused) && !m.Status(MonsExhausted) {
+		if g.Player.Sees(m.P) && g.randInt(2) == 0 && !m.Status(MonsConfused) && !m.Status(MonsExhausted) {
 			g.PrintStyled("Crazy Imp: “♫ larilon, larila ♫ ♪”", logSpecial)
 			g.MakeNoise(SingingNoise, m.P)
 			//g.ui.MusicAnimation(m.Pos)
@@ -978,8 +1006,8 @@ func (m *monster) HandleWatching(g *game) {
 	if m.Kind == MonsHazeCat {
 		turns = 3
 	}
-	if m.Watching+RandInt(2) < turns {
-		m.Alternate()
+	if m.Watching+g.randInt(2) < turns {
+		m.Alternate(g)
 		m.Watching++
 		if m.Kind == MonsDog {
 			dij := &monPath{g: g, monster: m}
@@ -994,7 +1022,7 @@ func (m *monster) HandleWatching(g *game) {
 		m.Target = m.NextTarget(g)
 		switch g.Bands[m.Band].Beh {
 		case BehGuard:
-			m.Alternate()
+			m.Alternate(g)
 			if m.P != m.Target {
 				m.MakeWander()
 				m.GatherBand(g)
@@ -1049,7 +1077,7 @@ func (m *monster) HandleEndPath(g *game) {
 		if !m.Peaceful(g) {
 			if !m.SeesPlayer(g) {
 				m.StartWatching()
-				m.Alternate()
+				m.Alternate(g)
 			}
 		} else {
 			m.Target = m.NextTarget(g)
@@ -1121,7 +1149,7 @@ func (m *monster) HandleMove(g *game) bool {
 			}
 			m.Path = m.Path[1:]
 		}
-	case (mons.P == target && m.P == monstarget || m.Waiting > 5+RandInt(2)) && !mons.Status(MonsLignified):
+	case (mons.P == target && m.P == monstarget || m.Waiting > 5+g.randInt(2)) && !mons.Status(MonsLignified):
 		target := mons.P
 		m.MoveTo(g, target)
 		m.Path = m.Path[1:]
@@ -1129,7 +1157,7 @@ func (m *monster) HandleMove(g *game) bool {
 			mons.Path = mons.Path[1:]
 		}
 	case m.State == Hunting && mons.State != Hunting:
-		if m.Waiting > 2+RandInt(3) {
+		if m.Waiting > 2+g.randInt(3) {
 			if mons.Peaceful(g) {
 				mons.MakeWander()
 			} else {
@@ -1141,7 +1169,7 @@ func (m *monster) HandleMove(g *game) bool {
 		}
 		m.Waiting++
 	case !mons.SeesPlayer(g) && mons.State != Hunting:
-		if m.Waiting > 1+RandInt(2) && mons.Kind != MonsSatowalgaPlant {
+		if m.Waiting > 1+g.randInt(2) && mons.Kind != MonsSatowalgaPlant {
 			mons.MakeWanderAt(mons.RandomFreeNeighbor(g))
 		} else {
 			m.Path = m.APath(g, m.P, m.Target)
@@ -1173,7 +1201,7 @@ func (m *monster) handleTurn(g *game) {
 	mpos := m.P
 	m.MakeAware(g)
 	if m.State == Resting {
-		if RandInt(3000) == 0 || m.Kind.ShallowSleep() && RandInt(10) == 0 {
+		if g.randInt(3000) == 0 || m.Kind.ShallowSleep() && g.randInt(10) == 0 {
 			m.NaturalAwake(g)
 		}
 		return
@@ -1238,7 +1266,7 @@ func (m *monster) InvertFoliage(g *game) {
 }
 
 func (m *monster) Exhaust(g *game) {
-	m.ExhaustTime(g, DurationExhaustionMonster+RandInt(DurationExhaustionMonster/2))
+	m.ExhaustTime(g, DurationExhaustionMonster+g.randInt(DurationExhaustionMonster/2))
 }
 
 func (m *monster) ExhaustTime(g *game, t int) {
@@ -1250,7 +1278,7 @@ func (m *monster) HitPlayer(g *game) {
 		return
 	}
 	dmg := m.Attack
-	clang := RandInt(4) == 0
+	clang := g.randInt(4) == 0
 	noise := g.HitNoise(clang)
 	g.MakeNoise(noise, g.Player.P)
 	var sclang string
@@ -1366,7 +1394,7 @@ func (m *monster) HitSideEffects(g *game) {
 		g.PlacePlayerAt(m.P)
 		g.PrintStyled("The flying milfid makes you swap positions.", logNotable)
 		g.StoryPrintf("Position swap by %s", m.Kind)
-		m.ExhaustTime(g, 5+RandInt(5))
+		m.ExhaustTime(g, 5+g.randInt(5))
 		if terrain(g.Dungeon.Cell(g.Player.P)) == ChasmCell {
 			g.PushEventFirst(&playerEvent{Action: AbyssFall}, g.Turn)
 		}
@@ -1524,7 +1552,7 @@ func (g *game) BarrierCandidates(p gruid.Point, dir gruid.Point) []gruid.Point {
 			candidates[len(candidates)-1], candidates[i] = candidates[i], candidates[len(candidates)-1]
 		}
 	}
-	if len(candidates) == 4 && RandInt(2) == 0 {
+	if len(candidates) == 4 && g.randInt(2) == 0 {
 		candidates[1], candidates[2] = candidates[2], candidates[1]
 	}
 	if len(candidates) == 4 {
@@ -1599,7 +1627,7 @@ func (m *monster) ThrowJavelin(g *game) bool {
 		return false
 	}
 	dmg := DmgNormal
-	clang := RandInt(4) == 0
+	clang := g.randInt(4) == 0
 	noise := g.HitNoise(clang)
 	var sclang string
 	if clang {
@@ -1610,14 +1638,14 @@ func (m *monster) ThrowJavelin(g *game) bool {
 	g.md.MonsterJavelinAnimation(g.Ray(m.P), true)
 	g.MakeNoise(noise, g.Player.P)
 	m.InflictDamage(g, dmg, dmg)
-	m.ExhaustTime(g, 10+RandInt(5))
+	m.ExhaustTime(g, 10+g.randInt(5))
 	return true
 }
 
 func (m *monster) Corrode(g *game) {
 	count := 0
 	for i := range g.Player.Magaras {
-		n := RandInt(2)
+		n := g.randInt(2)
 		g.Player.Magaras[i].Charges -= n
 		if g.Player.Magaras[i].Charges < 0 {
 			g.Player.Magaras[i].Charges = 0
@@ -1704,7 +1732,7 @@ func (m *monster) AbsorbMana(g *game) bool {
 	g.Player.MP -= 1
 	g.Printf("%s absorbs your mana.", m.Kind.Definite(true))
 	g.StoryPrintf("Mana absorbed by %s (MP: %d)", m.Kind, g.Player.MP)
-	m.ExhaustTime(g, 1+RandInt(2))
+	m.ExhaustTime(g, 1+g.randInt(2))
 	return true
 }
 
@@ -1723,7 +1751,9 @@ func (m *monster) MakeHunt(g *game) (noticed bool) {
 	if m.State != Hunting {
 		m.State = Hunting
 		g.Stats.NSpotted++
+		ConductUnseen.Break(g)
 		g.Stats.DSpotted[g.Depth]++
+		addHeat(&g.Stats.DSpottedAt, g.Depth, g.Player.P)
 		if !m.Alerted {
 			g.Stats.NUSpotted++
 			g.Stats.DUSpotted[g.Depth]++
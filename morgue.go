@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// morgueTimeFormat is the time format of the names of morgue directories,
+// which contain the dump and replays of finished games.
+const morgueTimeFormat = "20060102-150405"
+
+// morgueEntry describes an archived game.
+type morgueEntry struct {
+	Name     string // directory name
+	Time     time.Time
+	Daily    string
+	Wizard   bool
+	Outcome  dumpOutcome
+	Timeline []timelineEntry
+	Known    bool // outcome and timeline are known from the JSON dump
+}
+
+// newMorgueEntry returns the entry of an archived game, given its directory
+// name and JSON dump, if any.
+func newMorgueEntry(name string, data []byte) morgueEntry {
+	e := morgueEntry{Name: name}
+	e.Time, _ = time.ParseInLocation(morgueTimeFormat, name, time.Local)
+	var d struct {
+		Daily    string
+		Wizard   bool
+		Outcome  dumpOutcome
+		Timeline []timelineEntry
+	}
+	if data != nil && json.Unmarshal(data, &d) == nil {
+		e.Daily = d.Daily
+		e.Wizard = d.Wizard
+		e.Outcome = d.Outcome
+		e.Timeline = d.Timeline
+		e.Known = true
+	}
+	return e
+}
+
+// Summary returns a one-line summary of the archived game.
+func (e morgueEntry) Summary() string {
+	date := e.Name
+	if !e.Time.IsZero() {
+		date = e.Time.Format("2006-01-02 15:04")
+	}
+	if !e.Known {
+		return fmt.Sprintf("%s unknown", date)
+	}
+	outcome := "abandoned"
+	switch {
+	case e.Outcome.Died:
+		outcome = "died"
+	case e.Outcome.Escaped:
+		outcome = "escaped"
+	}
+	s := fmt.Sprintf("%s %-9s depth %2d, %5d turns", date, outcome, e.Outcome.MaxDepth, e.Outcome.Turns)
+	if e.Outcome.LiberatedShaedra {
+		s += ", Shaedra"
+	}
+	if e.Outcome.LiberatedArtifact {
+		s += ", Artifact"
+	}
+	if e.Daily != "" {
+		s += " (daily)"
+	}
+	if e.Wizard {
+		s += " (wizard)"
+	}
+	return s
+}
+
+func (md *model) openMorgue() {
+	entries, err := LoadMorgue()
+	if err != nil {
+		md.g.PrintfStyled("Error loading game history: %v", logError, err)
+		md.mode = modeNormal
+		return
+	}
+	if len(entries) == 0 {
+		md.g.Print("No finished game yet.")
+		md.mode = modeNormal
+		return
+	}
+	md.morgue = entries
+	mentries := []ui.MenuEntry{}
+	for _, e := range entries {
+		mentries = append(mentries, ui.MenuEntry{Text: ui.Text(" " + e.Summary())})
+	}
+	altBgEntries(mentries)
+	md.keysMenu.SetBox(&ui.Box{Title: ui.Text("Game History").WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	md.keysMenu.SetEntries(mentries)
+	md.mode = modeMenu
+	md.menuMode = modeMorgue
+}
+
+func (md *model) updateMorgueMenu(msg gruid.Msg) gruid.Effect {
+	md.keysMenu.Update(msg)
+	switch md.keysMenu.Action() {
+	case ui.MenuQuit:
+		md.mode = modeNormal
+	case ui.MenuInvoke:
+		e := md.morgue[md.keysMenu.Active()]
+		dump, err := LoadMorgueDump(e.Name)
+		if err != nil {
+			md.g.PrintfStyled("Error loading game dump: %v", logError, err)
+			md.mode = modeNormal
+			break
+		}
+		md.dumpView = dumpView{
+			title:      e.Summary(),
+			lines:      strings.Split(strings.TrimRight(dump, "\n"), "\n"),
+			timeline:   e.Timeline,
+			filterable: e.Known,
+		}
+		md.showMorgueDump()
+		md.pager.SetCursor(gruid.Point{0, 0})
+	}
+	return nil
+}
+
+// dumpView is a game dump shown in the pager, with a filter on its timeline.
+type dumpView struct {
+	title      string
+	lines      []string
+	timeline   []timelineEntry
+	filterable bool // whether the timeline is known and can be filtered
+	filter     int  // 0 for the whole timeline, or index in timelineKinds plus one
+}
+
+// Lines returns the lines of the dump, with only the timeline entries that
+// match the filter. It also returns the line of the timeline's header, or -1
+// if there is none.
+func (d dumpView) Lines() ([]string, int) {
+	start := -1
+	for i, l := range d.lines {
+		if l == "Timeline:" {
+			start = i
+			break
+		}
+	}
+	if start < 0 || d.filter == 0 {
+		return d.lines, start
+	}
+	end := start + 1
+	for end < len(d.lines) && d.lines[end] != "" {
+		end++
+	}
+	lines := append([]string{}, d.lines[:start+1]...)
+	entries := filterTimeline(d.timeline, timelineKinds[d.filter-1])
+	if len(entries) == 0 {
+		lines = append(lines, "(no entries)")
+	}
+	for _, e := range entries {
+		lines = append(lines, e.String())
+	}
+	return append(lines, d.lines[end:]...), start
+}
+
+// Filter returns the name of the timeline filter.
+func (d dumpView) Filter() string {
+	if d.filter == 0 {
+		return "all"
+	}
+	return string(timelineKinds[d.filter-1])
+}
+
+// Title returns the pager title of the dump.
+func (d dumpView) Title() string {
+	if !d.filterable {
+		return fmt.Sprintf(" %s ", d.title)
+	}
+	return fmt.Sprintf(" %s, timeline: %s (< > or tab to filter) ", d.title, d.Filter())
+}
+
+// setDumpPager puts the dump in the pager, with its current timeline filter.
+func (md *model) setDumpPager() {
+	d := md.dumpView
+	lines, _ := d.Lines()
+	stts := []ui.StyledText{}
+	for _, l := range lines {
+		stts = append(stts, ui.Text(l))
+	}
+	md.pager.SetBox(&ui.Box{Title: ui.Text(d.Title()).WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	md.pager.SetLines(stts)
+}
+
+// showMorgueDump shows the archived game dump in the pager.
+func (md *model) showMorgueDump() {
+	md.setDumpPager()
+	md.mode = modePager
+	md.pagerMode = modeMorgueDump
+}
+
+// updateDumpFilter changes the timeline filter of the dump in the pager on
+// filter keys, and shows the timeline.
+func (md *model) updateDumpFilter(msg gruid.Msg) {
+	kd, ok := msg.(gruid.MsgKeyDown)
+	if !ok || !md.dumpView.filterable {
+		return
+	}
+	n := len(timelineKinds) + 1
+	switch kd.Key {
+	case ">", gruid.KeyTab:
+		md.dumpView.filter = (md.dumpView.filter + 1) % n
+	case "<":
+		md.dumpView.filter = (md.dumpView.filter + n - 1) % n
+	default:
+		return
+	}
+	md.setDumpPager()
+	if _, start := md.dumpView.Lines(); start >= 0 {
+		md.pager.SetCursor(gruid.Point{0, start})
+	}
+}
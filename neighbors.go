@@ -24,16 +24,3 @@ func (g *game) flammableNeighbors(p gruid.Point) []gruid.Point {
 		return valid(q) && g.Dungeon.Cell(q).Flammable()
 	})
 }
-
-func randomNeighbor(p gruid.Point) gruid.Point {
-	switch RandInt(6) {
-	case 0, 1:
-		return p.Add(gruid.Point{1, 0})
-	case 2, 3:
-		return p.Add(gruid.Point{-1, 0})
-	case 4:
-		return p.Add(gruid.Point{0, 1})
-	default:
-		return p.Add(gruid.Point{0, -1})
-	}
-}
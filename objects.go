@@ -302,7 +302,7 @@ func (g *game) ActivateNightStone() error {
 		g.Printf("%s falls asleep.", mons.Kind.Definite(true))
 		mons.State = Resting
 		mons.Dir = ZP
-		mons.ExhaustTime(g, 4+RandInt(2))
+		mons.ExhaustTime(g, 4+g.randInt(2))
 	}
 	return nil
 }
@@ -354,7 +354,8 @@ func (g *game) TeleportToBarrel() {
 	for p := range g.Objects.Barrels {
 		barrels = append(barrels, p)
 	}
-	p := barrels[RandInt(len(barrels))]
+	sortPositions(barrels)
+	p := barrels[g.randInt(len(barrels))]
 	op := g.Player.P
 	g.Print("You teleport away.")
 	g.md.TeleportAnimation(op, p, true)
@@ -722,7 +723,7 @@ func (g *game) RandomCloak() (it item) {
 		CloakConversion}
 loop:
 	for {
-		it = cloaks[RandInt(len(cloaks))]
+		it = cloaks[g.randInt(len(cloaks))]
 		for _, cl := range g.GeneratedCloaks {
 			if cl == it {
 				continue loop
@@ -733,15 +734,16 @@ loop:
 	return it
 }
 
+var amulets = []item{AmuletTeleport,
+	AmuletConfusion,
+	AmuletFog,
+	AmuletLignification,
+	AmuletObstruction}
+
 func (g *game) RandomAmulet() (it item) {
-	amulets := []item{AmuletTeleport,
-		AmuletConfusion,
-		AmuletFog,
-		AmuletLignification,
-		AmuletObstruction}
 loop:
 	for {
-		it = amulets[RandInt(len(amulets))]
+		it = amulets[g.randInt(len(amulets))]
 		for _, cl := range g.GeneratedAmulets {
 			if cl == it {
 				continue loop
@@ -98,6 +98,10 @@ func (tp *tunnelPath) Neighbors(p gruid.Point) []gruid.Point {
 }
 
 func (tp *tunnelPath) Cost(from, to gruid.Point) int {
+	if tp.dg.vaultCells[from] {
+		// tunnels should not break into vaults
+		return 1000
+	}
 	if tp.dg.room[from] && !tp.dg.tunnel[from] {
 		return 50
 	}
@@ -211,7 +211,7 @@ func (g *game) Rest() error {
 	// TODO: animation
 	//g.ui.DrawMessage("Resting...")
 	g.Resting = true
-	g.RestingTurns = RandInt(5) // you do not wake up when you want
+	g.RestingTurns = g.randInt(5) // you do not wake up when you want
 	g.Player.Bananas--
 	return nil
 }
@@ -281,9 +281,7 @@ func (g *game) CollectGround() {
 				g.StoryPrintf("Found banana (bananas: %d)", g.Player.Bananas)
 				g.Dungeon.SetCell(p, GroundCell)
 				delete(g.Objects.Bananas, p)
-				if g.Player.Bananas == MaxBananas {
-					AchBananaCollector.Get(g)
-				}
+				g.CheckAchievements(CounterBananas)
 			}
 		case MagaraCell:
 			for i, mag := range g.Player.Magaras {
@@ -334,12 +332,18 @@ func (g *game) FallAbyss(style descendstyle) {
 	}
 	if style == DescendFall && g.Depth == MaxDepth || g.Depth == WinDepth {
 		g.Player.HP = 0
+		g.Stats.KilledBy = "fall into the abyss"
 		return
 	}
 	g.Descend(style)
 }
 
 func (g *game) AbyssJumpConfirmation() {
+	if g.md == nil {
+		// headless game: no confirmation
+		g.FallAbyss(DescendJump)
+		return
+	}
 	g.PrintStyled("Do you really want to jump into the abyss? (DANGEROUS) [y/N]", logConfirm)
 	g.md.mode = modeJumpConfirmation
 }
@@ -399,15 +403,7 @@ func (g *game) PlayerBump(p gruid.Point) (again bool, err error) {
 			g.Fog(p, 1)
 			g.Stats.Digs++
 			g.Stats.DestructionUse++
-			if g.Stats.DestructionUse == 20 {
-				AchDestructorNovice.Get(g)
-			}
-			if g.Stats.DestructionUse == 40 {
-				AchDestructorInitiate.Get(g)
-			}
-			if g.Stats.DestructionUse == 60 {
-				AchDestructorMaster.Get(g)
-			}
+			g.CheckAchievements(CounterDestructionUse)
 		}
 		if g.Player.Inventory.Body == CloakSmoke {
 			_, ok := g.Clouds[g.Player.P]
@@ -428,7 +424,11 @@ func (g *game) PlayerBump(p gruid.Point) (again bool, err error) {
 		if !g.Player.HasStatus(StatusSwift) {
 			g.Print("You no longer feel swift.")
 		}
-		g.md.updateMapInfo()
+		if g.md != nil {
+			g.md.updateMapInfo()
+		} else {
+			g.ComputeMapInfo()
+		}
 		return again, nil
 	}
 	return again, nil
@@ -441,7 +441,7 @@ func (g *game) SwiftFog() {
 		_, ok := g.Clouds[n.P]
 		if !ok && g.Dungeon.Cell(n.P).AllowsFog() {
 			g.Clouds[n.P] = CloudFog
-			g.PushEvent(&posEvent{P: n.P, Action: CloudEnd}, g.Turn+DurationFog+RandInt(DurationFog/2))
+			g.PushEvent(&posEvent{P: n.P, Action: CloudEnd}, g.Turn+DurationFog+g.randInt(DurationFog/2))
 		}
 	}
 	g.PutStatus(StatusSwift, DurationShortSwiftness)
@@ -457,7 +457,8 @@ func (g *game) Confusion() {
 
 // PlacePlayerAt moves the player to a given position, swapping positions with
 // a monster if necessary, and handles LOS and monsters awareness update,
-// ground collecting, and footsteps noise.
+// ground collecting, and footsteps noise, as well as swimming noise in
+// flooded caves.
 func (g *game) PlacePlayerAt(p gruid.Point) {
 	if p == g.Player.P {
 		return
@@ -466,6 +467,7 @@ func (g *game) PlacePlayerAt(p gruid.Point) {
 	m := g.MonsterAt(p)
 	ppos := g.Player.P
 	g.Player.P = p
+	addHeat(&g.Stats.DVisits, g.Depth, p)
 	if m.Exists() {
 		m.MoveTo(g, ppos)
 		m.Swapped = true
@@ -474,6 +476,10 @@ func (g *game) PlacePlayerAt(p gruid.Point) {
 		g.MakeNoise(QueenRockFootstepNoise, g.Player.P)
 		g.Print("Tap-tap.")
 	}
+	if g.Layout == FloodedCave && terrain(g.Dungeon.Cell(g.Player.P)) == WaterCell && !g.Player.HasStatus(StatusLevitation) {
+		g.MakeNoise(SwimNoise, g.Player.P)
+		g.Print("Splash.")
+	}
 	g.CollectGround()
 	g.ComputeLOS()
 	g.MakeMonstersAware()
@@ -492,9 +498,7 @@ func (g *game) ExtinguishFire() error {
 	g.Dungeon.SetCell(g.Player.P, ExtinguishedLightCell)
 	g.Objects.Lights[g.Player.P] = false
 	g.Stats.Extinguishments++
-	if g.Stats.Extinguishments >= 15 {
-		AchExtinguisher.Get(g)
-	}
+	g.CheckAchievements(CounterExtinguishments)
 	g.Print("You extinguish the fire.")
 	return nil
 }
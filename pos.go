@@ -1,6 +1,8 @@
 package main
 
 import (
+	"sort"
+
 	"github.com/anaseto/gruid"
 )
 
@@ -98,6 +100,13 @@ func idx(p gruid.Point) int {
 	return p.Y*DungeonWidth + p.X
 }
 
+// sortPositions sorts positions by index. It is used on slices built from map
+// iterations, so that random choices among them only depend on the game's
+// random source, and not on map iteration order.
+func sortPositions(ps []gruid.Point) {
+	sort.Slice(ps, func(i, j int) bool { return idx(ps[i]) < idx(ps[j]) })
+}
+
 func valid(p gruid.Point) bool {
 	return p.Y >= 0 && p.Y < DungeonHeight && p.X >= 0 && p.X < DungeonWidth
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// replayMark is an entry of a replay index: it associates a moment of a
+// recorded game with its depth and turn, and possibly a notable event.
+type replayMark struct {
+	Time  time.Time
+	Kind  markKind
+	Depth int
+	Turn  int
+	Text  string `json:",omitempty"`
+}
+
+// markKind is the kind of a replay index entry.
+type markKind string
+
+// Kinds of replay index entries. Entries of kind markTurn only record the
+// current depth and turn, and are not listed as bookmarks.
+const (
+	markTurn        markKind = "turn"
+	markLevel       markKind = "level"
+	markStory       markKind = "story"
+	markCritical    markKind = "critical"
+	markAchievement markKind = "achievement"
+	markDeath       markKind = "death"
+)
+
+// storyMark returns the kind of the index entry for a timeline entry.
+func storyMark(e timelineEntry) markKind {
+	switch e.Kind {
+	case TimelineAchievement:
+		return markAchievement
+	case TimelineNearDeath:
+		return markCritical
+	}
+	return markStory
+}
+
+// replayIndexer is a model wrapper that writes an index of the frame replay
+// of a game: depth and turn changes, timeline entries, critical hits,
+// achievements and death.
+type replayIndexer struct {
+	m     gruid.Model // wrapped model
+	md    *model
+	enc   *json.Encoder
+	g     *game // indexed game
+	story int   // number of indexed timeline entries
+	depth int
+	turn  int
+	dead  bool
+}
+
+func newReplayIndexer(m gruid.Model, md *model, w io.Writer) *replayIndexer {
+	return &replayIndexer{m: m, md: md, enc: json.NewEncoder(w)}
+}
+
+func (ri *replayIndexer) Update(msg gruid.Msg) gruid.Effect {
+	eff := ri.m.Update(msg)
+	g := ri.md.g
+	if g == nil {
+		return eff
+	}
+	if g != ri.g {
+		ri.g = g
+		ri.story = 0
+		ri.dead = false
+		if _, ok := msg.(gruid.MsgInit); ok && !ri.md.newGame {
+			// loaded game: timeline entries from previous sessions
+			// are already indexed.
+			ri.story = len(g.Stats.Timeline)
+			ri.depth = g.Depth
+		}
+	}
+	now := time.Now()
+	if g.Depth > 0 && g.Depth != ri.depth {
+		ri.depth = g.Depth
+		ri.turn = g.Turn
+		ri.write(replayMark{Time: now, Kind: markLevel, Depth: g.Depth, Turn: g.Turn, Text: fmt.Sprintf("Entered depth %d", g.Depth)})
+	} else if g.Turn != ri.turn {
+		ri.turn = g.Turn
+		ri.write(replayMark{Time: now, Kind: markTurn, Depth: g.Depth, Turn: g.Turn})
+	}
+	for _, e := range g.Stats.Timeline[ri.story:] {
+		ri.write(replayMark{Time: now, Kind: storyMark(e), Depth: g.Depth, Turn: g.Turn, Text: e.Text})
+	}
+	ri.story = len(g.Stats.Timeline)
+	if g.Player.HP <= 0 && !ri.dead {
+		ri.dead = true
+		text := "Died"
+		if g.Stats.KilledBy != "" {
+			text = "Killed by " + g.Stats.KilledBy
+		}
+		ri.write(replayMark{Time: now, Kind: markDeath, Depth: g.Depth, Turn: g.Turn, Text: text})
+	}
+	return eff
+}
+
+func (ri *replayIndexer) write(m replayMark) {
+	ri.enc.Encode(m)
+}
+
+func (ri *replayIndexer) Draw() gruid.Grid {
+	return ri.m.Draw()
+}
+
+// LoadReplayIndex loads the index of a replay file, if any. A truncated last
+// entry is ignored.
+func LoadReplayIndex(replay string) ([]replayMark, error) {
+	f, err := os.Open(replay + ".index")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return decodeReplayIndex(f)
+}
+
+func decodeReplayIndex(r io.Reader) ([]replayMark, error) {
+	marks := []replayMark{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var m replayMark
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			// truncated tail
+			break
+		}
+		marks = append(marks, m)
+	}
+	if err := sc.Err(); err != nil {
+		return marks, err
+	}
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].Time.Before(marks[j].Time) })
+	return marks, nil
+}
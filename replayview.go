@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// replayFrameTimes returns the times of the frames of a replay.
+func replayFrameTimes(fd *gruid.FrameDecoder) ([]time.Time, error) {
+	times := []time.Time{}
+	for {
+		var frame gruid.Frame
+		err := fd.Decode(&frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return times, err
+		}
+		times = append(times, frame.Time)
+	}
+	if len(times) == 0 {
+		return nil, errors.New("empty replay")
+	}
+	return times, nil
+}
+
+// replayViewMode describes what the replay viewer shows on top of the replay.
+type replayViewMode int
+
+const (
+	replayViewNormal replayViewMode = iota
+	replayViewBookmarks
+	replayViewHelp
+)
+
+// replayViewer is a model that shows a frame replay, with bookmarks from the
+// replay's index to jump to notable events, and a status line with the
+// current depth and turn.
+type replayViewer struct {
+	rep       *ui.Replay   // replay engine
+	times     []time.Time  // frame times
+	marks     []replayMark // index entries, sorted by time
+	bookmarks []replayMark // listed index entries
+	n         int          // number of applied frames
+	auto      bool         // automatic play
+	speed     time.Duration
+	status    bool // show status line
+	mode      replayViewMode
+	menu      *ui.Menu
+	pager     *ui.Pager
+	gd        gruid.Grid
+}
+
+func newReplayViewer(fd *gruid.FrameDecoder, times []time.Time, marks []replayMark) *replayViewer {
+	v := &replayViewer{
+		rep:    ui.NewReplay(ui.ReplayConfig{Grid: gruid.NewGrid(UIWidth, UIHeight), FrameDecoder: fd}),
+		times:  times,
+		marks:  marks,
+		auto:   true,
+		speed:  1,
+		status: true,
+		gd:     gruid.NewGrid(UIWidth, UIHeight),
+	}
+	for _, m := range marks {
+		if m.Kind != markTurn {
+			v.bookmarks = append(v.bookmarks, m)
+		}
+	}
+	v.menu = ui.NewMenu(ui.MenuConfig{
+		Grid:  gruid.NewGrid(UIWidth, UIHeight-1),
+		Box:   &ui.Box{Title: ui.Text("Bookmarks")},
+		Style: ui.MenuStyle{Active: gruid.Style{}.WithFg(ColorYellow)},
+		Keys:  ui.MenuKeys{Quit: []gruid.Key{gruid.KeySpace, "x", "X", gruid.KeyEscape}},
+	})
+	entries := []ui.MenuEntry{}
+	for _, m := range v.bookmarks {
+		entries = append(entries, ui.MenuEntry{
+			Text: ui.Text(fmt.Sprintf("Depth %2d Turn %5d %-13s %s", m.Depth, m.Turn, "["+string(m.Kind)+"]", m.Text)),
+		})
+	}
+	v.menu.SetEntries(entries)
+	v.pager = ui.NewPager(ui.PagerConfig{
+		Grid: gruid.NewGrid(UIWidth, UIHeight-1),
+		Box:  &ui.Box{Title: ui.Text("Replay keys")},
+		Keys: ui.PagerKeys{Quit: []gruid.Key{gruid.KeySpace, "x", "X", gruid.KeyEscape}},
+	})
+	lines := []ui.StyledText{}
+	for _, l := range [][2]string{
+		{"Quit", "q or escape"},
+		{"Pause", "p or space"},
+		{"Increase speed", "+ or }"},
+		{"Decrease speed", "- or {"},
+		{"Go to next frame", "right arrow or l"},
+		{"Go to previous frame", "left arrow or h"},
+		{"Go 1 minute forward", "down arrow or j"},
+		{"Go 1 minute backward", "up arrow or k"},
+		{"List bookmarks", "b or tab"},
+		{"Go to next bookmark", "n"},
+		{"Go to previous bookmark", "N"},
+		{"Toggle status line", "s"},
+		{"Show this help", "?"},
+	} {
+		lines = append(lines, ui.Textf("%-30s %s", l[0], l[1]))
+	}
+	v.pager.SetLines(lines)
+	return v
+}
+
+// maxReplayDelay is the maximum delay between two frames or inputs in a
+// replay view.
+const maxReplayDelay = 2 * time.Second
+
+// msgReplayTick asks for the next frame in automatic play.
+type msgReplayTick int
+
+func (v *replayViewer) Update(msg gruid.Msg) gruid.Effect {
+	switch v.mode {
+	case replayViewBookmarks:
+		v.menu.Update(msg)
+		switch v.menu.Action() {
+		case ui.MenuQuit:
+			v.mode = replayViewNormal
+		case ui.MenuInvoke:
+			v.mode = replayViewNormal
+			v.setFrame(v.frameAt(v.bookmarks[v.menu.Active()].Time))
+		}
+		return nil
+	case replayViewHelp:
+		v.pager.Update(msg)
+		if v.pager.Action() == ui.PagerQuit {
+			v.mode = replayViewNormal
+		}
+		return nil
+	}
+	switch msg := msg.(type) {
+	case gruid.MsgInit:
+		v.setFrame(1)
+		return v.tick()
+	case gruid.MsgQuit:
+		return gruid.End()
+	case msgReplayTick:
+		if !v.auto || int(msg) != v.n || v.n >= len(v.times) {
+			return nil
+		}
+		v.setFrame(v.n + 1)
+		return v.tick()
+	case gruid.MsgKeyDown:
+		return v.updateKeyDown(msg)
+	case gruid.MsgMouse:
+		switch msg.Action {
+		case gruid.MouseMain:
+			v.auto = !v.auto
+			return v.tick()
+		case gruid.MouseWheelDown:
+			v.auto = false
+			v.setFrame(v.n + 1)
+		case gruid.MouseWheelUp:
+			v.auto = false
+			v.setFrame(v.n - 1)
+		}
+	}
+	return nil
+}
+
+func (v *replayViewer) updateKeyDown(msg gruid.MsgKeyDown) gruid.Effect {
+	switch msg.Key {
+	case "q", "Q", gruid.KeyEscape:
+		return gruid.End()
+	case "p", "P", gruid.KeySpace:
+		v.auto = !v.auto
+	case "+", "}":
+		if v.speed < 64 {
+			v.speed *= 2
+		}
+	case "-", "{":
+		if v.speed > 1 {
+			v.speed /= 2
+		}
+	case gruid.KeyArrowRight, "l":
+		v.auto = false
+		v.setFrame(v.n + 1)
+	case gruid.KeyArrowLeft, "h":
+		v.auto = false
+		v.setFrame(v.n - 1)
+	case gruid.KeyArrowDown, "j":
+		v.setFrame(v.frameAt(v.time().Add(time.Minute)))
+	case gruid.KeyArrowUp, "k":
+		v.setFrame(v.frameAt(v.time().Add(-time.Minute)))
+	case "b", gruid.KeyTab:
+		if len(v.bookmarks) == 0 {
+			break
+		}
+		v.mode = replayViewBookmarks
+		i := v.bookmarkAfter(v.time())
+		if i == len(v.bookmarks) {
+			i--
+		}
+		v.menu.SetActive(i)
+	case "n":
+		for _, m := range v.bookmarks {
+			if n := v.frameAt(m.Time); n > v.n {
+				v.setFrame(n)
+				break
+			}
+		}
+	case "N":
+		for i := len(v.bookmarks) - 1; i >= 0; i-- {
+			if n := v.frameAt(v.bookmarks[i].Time); n < v.n {
+				v.setFrame(n)
+				break
+			}
+		}
+	case "s":
+		v.status = !v.status
+	case "?":
+		v.mode = replayViewHelp
+	}
+	return v.tick()
+}
+
+// setFrame sets the number of applied frames.
+func (v *replayViewer) setFrame(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(v.times) {
+		n = len(v.times)
+	}
+	v.rep.SetFrame(n)
+	v.n = n
+}
+
+// time returns the time of the current frame.
+func (v *replayViewer) time() time.Time {
+	return v.times[max(v.n-1, 0)]
+}
+
+// frameAt returns the number of applied frames needed to show the first frame
+// at or after a given time.
+func (v *replayViewer) frameAt(t time.Time) int {
+	return sort.Search(len(v.times), func(i int) bool { return !v.times[i].Before(t) }) + 1
+}
+
+// bookmarkAfter returns the index of the first bookmark at or after a given
+// time, or the number of bookmarks if there is none.
+func (v *replayViewer) bookmarkAfter(t time.Time) int {
+	return sort.Search(len(v.bookmarks), func(i int) bool { return !v.bookmarks[i].Time.Before(t) })
+}
+
+// markAt returns the last index entry at or before a given time.
+func (v *replayViewer) markAt(t time.Time) (replayMark, bool) {
+	i := sort.Search(len(v.marks), func(i int) bool { return v.marks[i].Time.After(t) })
+	if i == 0 {
+		return replayMark{}, false
+	}
+	return v.marks[i-1], true
+}
+
+// tick schedules the next frame in automatic play.
+func (v *replayViewer) tick() gruid.Effect {
+	if !v.auto || v.n >= len(v.times) {
+		return nil
+	}
+	d := v.times[v.n].Sub(v.time())
+	if d > maxReplayDelay {
+		d = maxReplayDelay
+	}
+	d /= v.speed
+	if d < time.Second/240 {
+		d = time.Second / 240
+	}
+	n := v.n
+	return gruid.Cmd(func() gruid.Msg {
+		time.Sleep(d)
+		return msgReplayTick(n)
+	})
+}
+
+// statusText returns the text of the status line.
+func (v *replayViewer) statusText() string {
+	state := "playing"
+	if !v.auto {
+		state = "paused"
+	}
+	if v.n >= len(v.times) {
+		state = "end"
+	}
+	elapsed := v.time().Sub(v.times[0]).Round(time.Second)
+	s := fmt.Sprintf(" %s x%d %v", state, v.speed, elapsed)
+	if m, ok := v.markAt(v.time()); ok {
+		s = fmt.Sprintf(" Depth %d Turn %d |%s", m.Depth, m.Turn, s)
+	}
+	return s + " | b: bookmarks ?: help"
+}
+
+func (v *replayViewer) Draw() gruid.Grid {
+	v.gd.Copy(v.rep.Draw())
+	switch v.mode {
+	case replayViewBookmarks:
+		v.gd.Copy(v.menu.Draw())
+		return v.gd
+	case replayViewHelp:
+		v.gd.Copy(v.pager.Draw())
+		return v.gd
+	}
+	if v.status {
+		line := v.gd.Slice(v.gd.Range().Line(UIHeight - 1))
+		st := gruid.Style{}.WithFg(ColorFgStatusOther).WithBg(ColorBackgroundSecondary)
+		line.Fill(gruid.Cell{Rune: ' ', Style: st})
+		ui.Text(v.statusText()).WithStyle(st).Draw(line)
+	}
+	return v.gd
+}
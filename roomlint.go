@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/rl"
+)
+
+// roomPlaceRunes returns the runes producing places of the given kind, for
+// error messages.
+func roomPlaceRunes(kind placeKind) string {
+	switch kind {
+	case PlaceDoor:
+		return "|"
+	case PlacePatrol:
+		return "P"
+	case PlaceStatic:
+		return "_"
+	case PlaceSpecialStatic:
+		return ">"
+	case PlaceItem:
+		return "!"
+	case PlaceStory:
+		return "SMΔA"
+	case PlacePatrolSpecial:
+		return "G"
+	}
+	return ""
+}
+
+// roomRequiredPlaces returns the kinds of places that the level generator
+// looks for in rooms of a template: each of the returned groups should have
+// at least one place of one of its kinds.
+func roomRequiredPlaces(tpl roomTemplate) [][]placeKind {
+	switch tpl.Kind {
+	case roomSpecial:
+		return [][]placeKind{{PlacePatrolSpecial}}
+	case roomVault:
+		return [][]placeKind{{PlacePatrol}, {PlaceItem}, {PlaceStatic}}
+	}
+	return [][]placeKind{{PlacePatrol}, {PlaceItem}, PlaceSpecialOrStatic}
+}
+
+// roomRequiredStory returns the story places that a special room of the
+// given role should provide.
+func roomRequiredStory(sr specialRoom) string {
+	switch sr {
+	case roomShaedra:
+		return "SMΔ"
+	case roomArtifact:
+		return "AMΔ"
+	}
+	return ""
+}
+
+// LintRoomTemplate returns the problems found in a room template: unknown
+// symbols, entries that do not reach every passable cell, and missing or
+// unreachable places. Obstacles (B) are assumed to be walls, as they may be.
+// Chasms are assumed crossable, as with levitation, and so are story places,
+// which may hide a few cells behind them.
+func LintRoomTemplate(tpl roomTemplate) []string {
+	v := &rl.Vault{}
+	v.SetRunes(roomRunes)
+	if err := v.Parse(tpl.Layout); err != nil {
+		return []string{err.Error()}
+	}
+	probs := []string{}
+	sz := v.Size()
+	switch {
+	case sz.X <= DungeonWidth-2 && sz.Y <= DungeonHeight-2:
+	case sz.Y <= DungeonWidth-2 && sz.X <= DungeonHeight-2:
+		probs = append(probs, "fits the map only when rotated (positions below are in the rotated layout)")
+		v.Rotate(1)
+	default:
+		return []string{fmt.Sprintf("too big to fit the map: %dx%d", sz.X, sz.Y)}
+	}
+	dg := &dgen{
+		d:    &dungeon{Grid: rl.NewGrid(DungeonWidth, DungeonHeight)},
+		room: map[gruid.Point]bool{},
+		rand: rand.New(rand.NewSource(1)),
+	}
+	r := &room{p: gruid.Point{1, 1}, vault: v, w: v.Size().X, h: v.Size().Y}
+	r.Dig(dg)
+	v.Iter(func(p gruid.Point, c rune) {
+		if c == 'B' {
+			dg.d.SetCell(r.p.Add(p), WallCell)
+		}
+	})
+	rel := func(p gruid.Point) gruid.Point { return p.Sub(r.p) }
+	if len(r.entries) == 0 {
+		probs = append(probs, "no entry (+ or -)")
+	}
+	passable := func(p gruid.Point) bool {
+		return dg.room[p] && dg.d.Cell(p).IsPassable()
+	}
+	crossable := func(p gruid.Point) bool {
+		c := dg.d.Cell(p)
+		return dg.room[p] && (c.IsLevitatePassable() || terrain(c) == StoryCell)
+	}
+	starts := []gruid.Point{}
+	for _, e := range r.entries {
+		starts = append(starts, e.p)
+		reached := dg.roomReach([]gruid.Point{e.p}, crossable)
+		if len(reached) == 0 {
+			probs = append(probs, fmt.Sprintf("entry at %v leads nowhere", rel(e.p)))
+			continue
+		}
+		unreached := invalidPos
+		v.Iter(func(p gruid.Point, c rune) {
+			q := r.p.Add(p)
+			if unreached == invalidPos && passable(q) && !reached[q] {
+				unreached = q
+			}
+		})
+		if unreached != invalidPos {
+			probs = append(probs, fmt.Sprintf("entry at %v does not reach %v", rel(e.p), rel(unreached)))
+		}
+	}
+	approachable := dg.roomReach(starts, crossable)
+	for _, pl := range r.places {
+		if pl.kind == PlaceStory && !approachable[pl.p] {
+			probs = append(probs, fmt.Sprintf("story place at %v cannot be approached", rel(pl.p)))
+		}
+	}
+	for _, group := range roomRequiredPlaces(tpl) {
+		found := false
+		runes := ""
+		for _, kind := range group {
+			runes += roomPlaceRunes(kind)
+			for _, pl := range r.places {
+				if pl.kind == kind {
+					found = true
+				}
+			}
+		}
+		if !found {
+			probs = append(probs, fmt.Sprintf("no %s place", strings.Join(strings.Split(runes, ""), " or ")))
+		}
+	}
+	for _, c := range roomRequiredStory(tpl.Special) {
+		if !strings.ContainsRune(v.Content(), c) {
+			probs = append(probs, fmt.Sprintf("no %c story place", c))
+		}
+	}
+	return probs
+}
+
+// roomReach returns the cells reached from some starting cells, such as room
+// entries, through the cells satisfying the given predicate.
+func (dg *dgen) roomReach(starts []gruid.Point, passable func(gruid.Point) bool) map[gruid.Point]bool {
+	reached := map[gruid.Point]bool{}
+	queue := append([]gruid.Point{}, starts...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, q := range dg.neighbors.Cardinal(p, passable) {
+			if !reached[q] {
+				reached[q] = true
+				queue = append(queue, q)
+			}
+		}
+	}
+	return reached
+}
+
+// roomPreviews returns the layouts of a vault in the orientations used by
+// the level generator.
+func roomPreviews(layout string) []string {
+	previews := []string{}
+	for _, reflect := range []bool{false, true} {
+		for n := 0; n < 4; n++ {
+			v := &rl.Vault{}
+			if err := v.Parse(layout); err != nil {
+				return nil
+			}
+			if reflect {
+				v.Reflect()
+			}
+			v.Rotate(n)
+			previews = append(previews, v.Content())
+		}
+	}
+	return previews
+}
+
+// writeSideBySide writes text blocks side by side, wrapping to a new row of
+// blocks when the width would exceed a limit.
+func writeSideBySide(w io.Writer, blocks []string, width int) {
+	for len(blocks) > 0 {
+		row := [][]string{}
+		rwidth := 0
+		for len(blocks) > 0 {
+			lines := strings.Split(blocks[0], "\n")
+			bw := len([]rune(lines[0]))
+			if len(row) > 0 && rwidth+2+bw > width {
+				break
+			}
+			row = append(row, lines)
+			rwidth += bw + 2
+			blocks = blocks[1:]
+		}
+		height := 0
+		for _, lines := range row {
+			height = max(height, len(lines))
+		}
+		for y := 0; y < height; y++ {
+			line := ""
+			for _, lines := range row {
+				bw := len([]rune(lines[0]))
+				if y < len(lines) {
+					line += lines[y] + "  "
+				} else {
+					line += strings.Repeat(" ", bw+2)
+				}
+			}
+			fmt.Fprintln(w, strings.TrimRight(line, " "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// LintRooms checks the room templates in use and the vault layouts, and
+// writes a report with previews of each template in every orientation. It
+// returns an error if some template has problems.
+func LintRooms(w io.Writer) error {
+	bad := 0
+	tpls := append([]roomTemplate{}, roomTemplates...)
+	for _, vd := range vaults {
+		tpls = append(tpls, vd.Room)
+	}
+	for _, tpl := range tpls {
+		probs := LintRoomTemplate(tpl)
+		status := "ok"
+		if len(probs) > 0 {
+			status = fmt.Sprintf("%d problem(s)", len(probs))
+			bad++
+		}
+		desc := tpl.Kind.String()
+		if tpl.Kind == roomSpecial {
+			desc = tpl.Special.String()
+		}
+		fmt.Fprintf(w, "%s (%s, weight %d): %s\n", tpl.Name, desc, tpl.Weight, status)
+		for _, prob := range probs {
+			fmt.Fprintf(w, "  %s\n", prob)
+		}
+		writeSideBySide(w, roomPreviews(tpl.Layout), 80)
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d room template(s) with problems", bad)
+	}
+	return nil
+}
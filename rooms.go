@@ -83,17 +83,17 @@ func (r *room) Dig(dg *dgen) {
 		case 'B':
 			// obstacle
 			t := WallCell
-			switch RandInt(9) {
+			switch dg.rand.Intn(9) {
 			case 0, 6:
 				t = TreeCell
 			case 1:
-				if RandInt(2) == 0 {
+				if dg.rand.Intn(2) == 0 {
 					t = QueenRockCell
 				} else {
 					t = LightCell
 				}
 			case 2:
-				if RandInt(2) == 0 {
+				if dg.rand.Intn(2) == 0 {
 					t = ChasmCell
 				} else {
 					t = TableCell
@@ -205,7 +205,7 @@ func (r *room) Dig(dg *dgen) {
 
 // UnusedEntry returns an unused entry, if possible, or a random entry
 // otherwise.
-func (r *room) UnusedEntry() int {
+func (r *room) UnusedEntry(dg *dgen) int {
 	ens := []int{}
 	for i, e := range r.entries {
 		if !e.used {
@@ -213,12 +213,12 @@ func (r *room) UnusedEntry() int {
 		}
 	}
 	if len(ens) == 0 {
-		return RandInt(len(r.entries))
+		return dg.rand.Intn(len(r.entries))
 	}
-	return ens[RandInt(len(ens))]
+	return ens[dg.rand.Intn(len(ens))]
 }
 
-func (r *room) RandomPlace(kind placeKind) gruid.Point {
+func (r *room) RandomPlace(dg *dgen, kind placeKind) gruid.Point {
 	var p []int
 	for i, pl := range r.places {
 		if pl.kind == kind && !pl.used {
@@ -228,17 +228,17 @@ func (r *room) RandomPlace(kind placeKind) gruid.Point {
 	if len(p) == 0 {
 		return invalidPos
 	}
-	j := p[RandInt(len(p))]
+	j := p[dg.rand.Intn(len(p))]
 	r.places[j].used = true
 	return r.places[j].p
 }
 
 var PlaceSpecialOrStatic = []placeKind{PlaceSpecialStatic, PlaceStatic}
 
-func (r *room) RandomPlaces(kinds []placeKind) gruid.Point {
+func (r *room) RandomPlaces(dg *dgen, kinds []placeKind) gruid.Point {
 	p := invalidPos
 	for _, kind := range kinds {
-		p = r.RandomPlace(kind)
+		p = r.RandomPlace(dg, kind)
 		if p != invalidPos {
 			break
 		}
@@ -309,8 +309,6 @@ const (
 ???##+##???`
 )
 
-var roomNormalTemplates = []string{RoomAlmostSquare, RoomSquareBis, RoomRoundSimple, RoomLittle, RoomLittleDiamond, RoomLittleColumnDiamond, RoomRound, RoomLittleTreeDiamond, RoomRoundTree}
-
 const (
 	RoomBigColumns = `
 ?####?#++#?####?
@@ -655,14 +653,6 @@ const (
 ????########????`
 )
 
-var roomBigTemplates = []string{RoomBigColumns, RoomBigGarden, RoomColumns,
-	RoomRoundColumns, RoomRoundGarden, RoomLongHall, RoomGardenHall,
-	RoomTriangles, RoomHome1, RoomHome2, RoomHome3, RoomHome4, RoomHome5, RoomHome6,
-	RoomTriangle, RoomSpiraling, RoomSpiralingCircle, RoomAltar,
-	RoomCircleDouble, RoomGardenHome, RoomBigRooms, RoomCaban, RoomDolmen,
-	RoomSmallTemple, RoomTemple, RoomSchool, RoomTavern, RoomShop,
-	RoomDoctor, RoomRuins, RoomPillars, RoomRoundHall, RoomToilets, RoomPicnic, RoomSnake}
-
 const (
 	CellShaedra = `
 ?#?#?#?#?
@@ -714,8 +704,6 @@ c###_._###c
 
 // TODO: add indestructible walls?
 
-var roomCellTemplates = []string{CellShaedra, CellShaedra2, CellShaedra3, CellShaedra4}
-
 const (
 	RoomArtifact = `
 ????#????
@@ -749,8 +737,6 @@ P######?`
 ?###+###?`
 )
 
-var roomArtifactTemplates = []string{RoomArtifact, RoomArtifact2, RoomArtifact3}
-
 const (
 	RoomSpecialNixes = `
 ?#########???
@@ -924,28 +910,36 @@ const (
 	roomArtifact
 )
 
-func (sr specialRoom) Templates() (tpl []string) {
+func (sr specialRoom) String() (text string) {
 	switch sr {
+	case noSpecialRoom:
+		text = "none"
 	case roomMilfids:
-		tpl = append(tpl, RoomSpecialMilfids, RoomSpecialMilfids2)
+		text = "milfids"
 	case roomFrogs:
-		tpl = append(tpl, RoomSpecialFrogs)
+		text = "frogs"
+	case roomNixes:
+		text = "nixes"
 	case roomVampires:
-		tpl = append(tpl, RoomSpecialVampires, RoomSpecialVampires2)
+		text = "vampires"
 	case roomCelmists:
-		tpl = append(tpl, RoomSpecialCelmists, RoomSpecialCelmists2, RoomSpecialCelmists3)
-	case roomNixes:
-		tpl = append(tpl, RoomSpecialNixes)
+		text = "celmists"
 	case roomHarpies:
-		tpl = append(tpl, RoomSpecialHarpies, RoomSpecialHarpies2)
+		text = "harpies"
 	case roomTreeMushrooms:
-		tpl = append(tpl, RoomSpecialTreeMushrooms, RoomSpecialTreeMushrooms2)
+		text = "tree mushrooms"
 	case roomMirrorSpecters:
-		tpl = append(tpl, RoomSpecialMirrorSpecters, RoomSpecialMirrorSpecters2)
+		text = "mirror specters"
 	case roomShaedra:
-		tpl = roomCellTemplates
+		text = "Shaedra's cell"
 	case roomArtifact:
-		tpl = roomArtifactTemplates
+		text = "artifact"
 	}
-	return tpl
+	return text
+}
+
+// Templates returns the templates of the special room that can be used at
+// the game's depth.
+func (sr specialRoom) Templates(g *game) []roomTemplate {
+	return g.roomTemplatesFor(roomSpecial, sr, g.Depth)
 }
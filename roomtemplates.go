@@ -0,0 +1,354 @@
+package main
+
+// This file implements the room template registry. Built-in templates are
+// the layout constants of rooms.go. More templates can be loaded from text
+// files, each made of a header of "key: value" lines, an empty line, and
+// the layout:
+//
+//	kind: big
+//	depths: 3-8
+//	weight: 2
+//
+//	?####+####?
+//	#!..P.P..>#
+//	?####+####?
+//
+// Keys are optional:
+//
+//   - kind: "normal" (default) or "big" for rooms placed anywhere, or
+//     "special" for special rooms placed on the edge or center of a level.
+//   - special: special-room role of a special template, such as "nixes" or
+//     "Shaedra's cell".
+//   - depths: allowed depths, as a single depth or a range like "3-8".
+//   - weight: relative chance of picking the template among the others of
+//     its kind (1 by default). A zero weight disables the template.
+//
+// A loaded template replaces the built-in template with the same name.
+// Templates with problems found by LintRoomTemplate are rejected, and the
+// built-in templates are still used for a kind or special role for which the
+// loaded ones leave no template at some depth. Daily
+// challenges only use the built-in templates, so that every player gets the
+// same dungeon.
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/anaseto/gruid/rl"
+)
+
+// roomRunes are the runes allowed in room layouts.
+const roomRunes = ".>!P_|G-B#+TπlW\"?,~cqSMΔA"
+
+// roomKind is the placement kind of a room template.
+type roomKind int
+
+const (
+	roomNormal roomKind = iota
+	roomBig
+	roomSpecial
+	roomVault
+)
+
+func (rk roomKind) String() (text string) {
+	switch rk {
+	case roomNormal:
+		text = "normal"
+	case roomBig:
+		text = "big"
+	case roomSpecial:
+		text = "special"
+	case roomVault:
+		text = "vault"
+	}
+	return text
+}
+
+// roomTemplate is a room layout with its metadata.
+type roomTemplate struct {
+	Name     string
+	Layout   string
+	Kind     roomKind
+	Special  specialRoom // role of a special room
+	MinDepth int         // 0 means no minimum depth
+	MaxDepth int         // 0 means no maximum depth
+	Weight   int
+}
+
+// Allowed reports whether the template can be used at the given depth.
+func (tpl roomTemplate) Allowed(depth int) bool {
+	return tpl.Weight > 0 && (tpl.MinDepth == 0 || depth >= tpl.MinDepth) &&
+		(tpl.MaxDepth == 0 || depth <= tpl.MaxDepth)
+}
+
+func builtinRoom(name, layout string, kind roomKind, sr specialRoom) roomTemplate {
+	return roomTemplate{Name: name, Layout: layout, Kind: kind, Special: sr, Weight: 1}
+}
+
+// builtinRoomTemplates returns the built-in room templates.
+func builtinRoomTemplates() []roomTemplate {
+	return []roomTemplate{
+		builtinRoom("RoomAlmostSquare", RoomAlmostSquare, roomNormal, noSpecialRoom),
+		builtinRoom("RoomSquareBis", RoomSquareBis, roomNormal, noSpecialRoom),
+		builtinRoom("RoomRoundSimple", RoomRoundSimple, roomNormal, noSpecialRoom),
+		builtinRoom("RoomLittle", RoomLittle, roomNormal, noSpecialRoom),
+		builtinRoom("RoomLittleDiamond", RoomLittleDiamond, roomNormal, noSpecialRoom),
+		builtinRoom("RoomLittleColumnDiamond", RoomLittleColumnDiamond, roomNormal, noSpecialRoom),
+		builtinRoom("RoomRound", RoomRound, roomNormal, noSpecialRoom),
+		builtinRoom("RoomLittleTreeDiamond", RoomLittleTreeDiamond, roomNormal, noSpecialRoom),
+		builtinRoom("RoomRoundTree", RoomRoundTree, roomNormal, noSpecialRoom),
+		builtinRoom("RoomBigColumns", RoomBigColumns, roomBig, noSpecialRoom),
+		builtinRoom("RoomBigGarden", RoomBigGarden, roomBig, noSpecialRoom),
+		builtinRoom("RoomColumns", RoomColumns, roomBig, noSpecialRoom),
+		builtinRoom("RoomRoundColumns", RoomRoundColumns, roomBig, noSpecialRoom),
+		builtinRoom("RoomRoundGarden", RoomRoundGarden, roomBig, noSpecialRoom),
+		builtinRoom("RoomLongHall", RoomLongHall, roomBig, noSpecialRoom),
+		builtinRoom("RoomGardenHall", RoomGardenHall, roomBig, noSpecialRoom),
+		builtinRoom("RoomTriangles", RoomTriangles, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome1", RoomHome1, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome2", RoomHome2, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome3", RoomHome3, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome4", RoomHome4, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome5", RoomHome5, roomBig, noSpecialRoom),
+		builtinRoom("RoomHome6", RoomHome6, roomBig, noSpecialRoom),
+		builtinRoom("RoomTriangle", RoomTriangle, roomBig, noSpecialRoom),
+		builtinRoom("RoomSpiraling", RoomSpiraling, roomBig, noSpecialRoom),
+		builtinRoom("RoomSpiralingCircle", RoomSpiralingCircle, roomBig, noSpecialRoom),
+		builtinRoom("RoomAltar", RoomAltar, roomBig, noSpecialRoom),
+		builtinRoom("RoomCircleDouble", RoomCircleDouble, roomBig, noSpecialRoom),
+		builtinRoom("RoomGardenHome", RoomGardenHome, roomBig, noSpecialRoom),
+		builtinRoom("RoomBigRooms", RoomBigRooms, roomBig, noSpecialRoom),
+		builtinRoom("RoomCaban", RoomCaban, roomBig, noSpecialRoom),
+		builtinRoom("RoomDolmen", RoomDolmen, roomBig, noSpecialRoom),
+		builtinRoom("RoomSmallTemple", RoomSmallTemple, roomBig, noSpecialRoom),
+		builtinRoom("RoomTemple", RoomTemple, roomBig, noSpecialRoom),
+		builtinRoom("RoomSchool", RoomSchool, roomBig, noSpecialRoom),
+		builtinRoom("RoomTavern", RoomTavern, roomBig, noSpecialRoom),
+		builtinRoom("RoomShop", RoomShop, roomBig, noSpecialRoom),
+		builtinRoom("RoomDoctor", RoomDoctor, roomBig, noSpecialRoom),
+		builtinRoom("RoomRuins", RoomRuins, roomBig, noSpecialRoom),
+		builtinRoom("RoomPillars", RoomPillars, roomBig, noSpecialRoom),
+		builtinRoom("RoomRoundHall", RoomRoundHall, roomBig, noSpecialRoom),
+		builtinRoom("RoomToilets", RoomToilets, roomBig, noSpecialRoom),
+		builtinRoom("RoomPicnic", RoomPicnic, roomBig, noSpecialRoom),
+		builtinRoom("RoomSnake", RoomSnake, roomBig, noSpecialRoom),
+		builtinRoom("RoomSpecialMilfids", RoomSpecialMilfids, roomSpecial, roomMilfids),
+		builtinRoom("RoomSpecialMilfids2", RoomSpecialMilfids2, roomSpecial, roomMilfids),
+		builtinRoom("RoomSpecialFrogs", RoomSpecialFrogs, roomSpecial, roomFrogs),
+		builtinRoom("RoomSpecialVampires", RoomSpecialVampires, roomSpecial, roomVampires),
+		builtinRoom("RoomSpecialVampires2", RoomSpecialVampires2, roomSpecial, roomVampires),
+		builtinRoom("RoomSpecialCelmists", RoomSpecialCelmists, roomSpecial, roomCelmists),
+		builtinRoom("RoomSpecialCelmists2", RoomSpecialCelmists2, roomSpecial, roomCelmists),
+		builtinRoom("RoomSpecialCelmists3", RoomSpecialCelmists3, roomSpecial, roomCelmists),
+		builtinRoom("RoomSpecialNixes", RoomSpecialNixes, roomSpecial, roomNixes),
+		builtinRoom("RoomSpecialHarpies", RoomSpecialHarpies, roomSpecial, roomHarpies),
+		builtinRoom("RoomSpecialHarpies2", RoomSpecialHarpies2, roomSpecial, roomHarpies),
+		builtinRoom("RoomSpecialTreeMushrooms", RoomSpecialTreeMushrooms, roomSpecial, roomTreeMushrooms),
+		builtinRoom("RoomSpecialTreeMushrooms2", RoomSpecialTreeMushrooms2, roomSpecial, roomTreeMushrooms),
+		builtinRoom("RoomSpecialMirrorSpecters", RoomSpecialMirrorSpecters, roomSpecial, roomMirrorSpecters),
+		builtinRoom("RoomSpecialMirrorSpecters2", RoomSpecialMirrorSpecters2, roomSpecial, roomMirrorSpecters),
+		builtinRoom("CellShaedra", CellShaedra, roomSpecial, roomShaedra),
+		builtinRoom("CellShaedra2", CellShaedra2, roomSpecial, roomShaedra),
+		builtinRoom("CellShaedra3", CellShaedra3, roomSpecial, roomShaedra),
+		builtinRoom("CellShaedra4", CellShaedra4, roomSpecial, roomShaedra),
+		builtinRoom("RoomArtifact", RoomArtifact, roomSpecial, roomArtifact),
+		builtinRoom("RoomArtifact2", RoomArtifact2, roomSpecial, roomArtifact),
+		builtinRoom("RoomArtifact3", RoomArtifact3, roomSpecial, roomArtifact),
+	}
+}
+
+// builtinRooms contains the built-in room templates, used as they are in
+// daily challenges.
+var builtinRooms = builtinRoomTemplates()
+
+// roomTemplates contains the room templates used by the level generator: the
+// built-in ones, and then the ones added by AddRoomTemplates.
+var roomTemplates = builtinRoomTemplates()
+
+// userRooms contains the templates added by AddRoomTemplates.
+var userRooms []roomTemplate
+
+// AddRoomTemplates adds templates to the ones used by the level generator.
+// A template replaces an existing one with the same name.
+func AddRoomTemplates(tpls []roomTemplate) {
+	for _, tpl := range tpls {
+		replaced := false
+		for i := range roomTemplates {
+			if roomTemplates[i].Name == tpl.Name {
+				roomTemplates[i] = tpl
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			roomTemplates = append(roomTemplates, tpl)
+		}
+		userRooms = append(userRooms, tpl)
+	}
+}
+
+// CheckRoomTemplates returns the templates without lint problems, along with
+// an error describing the problems of the others, if any.
+func CheckRoomTemplates(tpls []roomTemplate) ([]roomTemplate, error) {
+	ok := []roomTemplate{}
+	rejected := []string{}
+	for _, tpl := range tpls {
+		probs := LintRoomTemplate(tpl)
+		if len(probs) > 0 {
+			rejected = append(rejected, fmt.Sprintf("%s: %s", tpl.Name, strings.Join(probs, "; ")))
+			continue
+		}
+		ok = append(ok, tpl)
+	}
+	if len(rejected) > 0 {
+		return ok, fmt.Errorf("rejected room template(s): %s", strings.Join(rejected, ", "))
+	}
+	return ok, nil
+}
+
+// userRoomsDigest returns a digest of the templates added by
+// AddRoomTemplates, or zero if there are none.
+func userRoomsDigest() uint64 {
+	if len(userRooms) == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	for _, tpl := range userRooms {
+		fmt.Fprintf(h, "%s %d %d %d %d %d\n%s\n", tpl.Name, tpl.Kind, tpl.Special,
+			tpl.MinDepth, tpl.MaxDepth, tpl.Weight, tpl.Layout)
+	}
+	return h.Sum64()
+}
+
+// roomTemplatesFor returns the templates of a given kind and special role
+// that can be used at the given depth. Daily challenges only use the
+// built-in templates. The built-in templates are used too when user
+// templates leave none for the kind and role at that depth, for example
+// when they disable every Shaedra's cell, as the level generator needs at
+// least one.
+func (g *game) roomTemplatesFor(kind roomKind, sr specialRoom, depth int) []roomTemplate {
+	if g.Daily != "" {
+		return filterRoomTemplates(builtinRooms, kind, sr, depth)
+	}
+	tpls := filterRoomTemplates(roomTemplates, kind, sr, depth)
+	if len(tpls) == 0 {
+		desc := kind.String()
+		if sr != noSpecialRoom {
+			desc = sr.String()
+		}
+		log.Printf("no %s room templates for depth %d: using built-in ones", desc, depth)
+		tpls = filterRoomTemplates(builtinRooms, kind, sr, depth)
+	}
+	return tpls
+}
+
+// filterRoomTemplates returns the templates of a given kind and special role
+// that can be used at the given depth.
+func filterRoomTemplates(all []roomTemplate, kind roomKind, sr specialRoom, depth int) []roomTemplate {
+	tpls := []roomTemplate{}
+	for _, tpl := range all {
+		if tpl.Kind == kind && tpl.Special == sr && tpl.Allowed(depth) {
+			tpls = append(tpls, tpl)
+		}
+	}
+	return tpls
+}
+
+// parseSpecialRoom returns the special room with the given name.
+func parseSpecialRoom(s string) (specialRoom, error) {
+	for sr := noSpecialRoom; sr <= roomArtifact; sr++ {
+		if sr.String() == s {
+			return sr, nil
+		}
+	}
+	return noSpecialRoom, fmt.Errorf("unknown special room “%s”", s)
+}
+
+// parseDepths parses a depth or a depth range.
+func parseDepths(s string) (min, max int, err error) {
+	fields := strings.SplitN(s, "-", 2)
+	min, err = strconv.Atoi(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	max = min
+	if len(fields) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if min < 1 || max > MaxDepth || min > max {
+		return 0, 0, fmt.Errorf("invalid depth range %d-%d", min, max)
+	}
+	return min, max, nil
+}
+
+// ParseRoomTemplate parses a room template file.
+func ParseRoomTemplate(name string, data []byte) (roomTemplate, error) {
+	tpl := roomTemplate{Name: name, Weight: 1}
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var header, layout string
+	if strings.HasPrefix(s, "\n") {
+		layout = s
+	} else {
+		i := strings.Index(s, "\n\n")
+		if i < 0 {
+			return tpl, fmt.Errorf("%s: missing empty line after header", name)
+		}
+		header, layout = s[:i], s[i+2:]
+	}
+	special := ""
+	for _, l := range strings.Split(header, "\n") {
+		if l == "" {
+			continue
+		}
+		fields := strings.SplitN(l, ":", 2)
+		if len(fields) != 2 {
+			return tpl, fmt.Errorf("%s: invalid header line “%s”", name, l)
+		}
+		key, value := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		var err error
+		switch key {
+		case "kind":
+			switch value {
+			case "normal":
+				tpl.Kind = roomNormal
+			case "big":
+				tpl.Kind = roomBig
+			case "special":
+				tpl.Kind = roomSpecial
+			default:
+				err = fmt.Errorf("unknown kind “%s”", value)
+			}
+		case "special":
+			special = value
+			tpl.Special, err = parseSpecialRoom(value)
+		case "depths":
+			tpl.MinDepth, tpl.MaxDepth, err = parseDepths(value)
+		case "weight":
+			tpl.Weight, err = strconv.Atoi(value)
+			if err == nil && tpl.Weight < 0 {
+				err = fmt.Errorf("negative weight")
+			}
+		default:
+			err = fmt.Errorf("unknown key “%s”", key)
+		}
+		if err != nil {
+			return tpl, fmt.Errorf("%s: %s: %v", name, key, err)
+		}
+	}
+	if (tpl.Kind == roomSpecial) != (tpl.Special != noSpecialRoom) {
+		return tpl, fmt.Errorf("%s: special rooms need a special role, and only them (kind %s, special “%s”)", name, tpl.Kind, special)
+	}
+	tpl.Layout = "\n" + strings.TrimSpace(layout)
+	v := &rl.Vault{}
+	v.SetRunes(roomRunes)
+	if err := v.Parse(tpl.Layout); err != nil {
+		return tpl, fmt.Errorf("%s: %v", name, err)
+	}
+	return tpl, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+// Score weights. The score rewards going deep, the quest goals, stealth and
+// conducts, and penalizes monster deaths and long games.
+const (
+	scorePerDepth       = 1000 // per explored level
+	scoreEscape         = 5000
+	scoreShaedra        = 3000
+	scoreArtifact       = 3000
+	scorePerStealth     = 10  // per level and percent of monsters that never spotted you
+	scorePerAchievement = 250 // NoAchievement does not count
+	scorePerKill        = -200
+	scorePerConduct     = 1000 // per conduct intact at the escape
+	scoreTurnsPerPoint  = 10   // one point lost every scoreTurnsPerPoint turns
+)
+
+// scoreDetail contains the components of the score of a game.
+type scoreDetail struct {
+	Depth        int
+	Escape       int
+	Shaedra      int
+	Artifact     int
+	Stealth      int
+	Achievements int
+	Conducts     int
+	Kills        int
+	Turns        int
+}
+
+// Total returns the score: the sum of its components, and at least zero.
+func (sd scoreDetail) Total() int {
+	return max(0, sd.Depth+sd.Escape+sd.Shaedra+sd.Artifact+sd.Stealth+sd.Achievements+sd.Conducts+sd.Kills+sd.Turns)
+}
+
+func (sd scoreDetail) String() string {
+	return fmt.Sprintf("%d (depth %d, escape %d, Shaedra %d, Artifact %d, stealth %d, achievements %d, conducts %d, kills %d, turns %d)",
+		sd.Total(), sd.Depth, sd.Escape, sd.Shaedra, sd.Artifact, sd.Stealth, sd.Achievements, sd.Conducts, sd.Kills, sd.Turns)
+}
+
+// ScoreDetail returns the components of the score of the game, computed from
+// its statistics.
+func (g *game) ScoreDetail() scoreDetail {
+	maxDepth := max(g.Depth, g.ExploredLevels)
+	sd := scoreDetail{
+		Depth: maxDepth * scorePerDepth,
+		Kills: g.Stats.Killed * scorePerKill,
+		Turns: -g.Turn / scoreTurnsPerPoint,
+	}
+	if g.Player.HP > 0 && g.Depth == -1 {
+		sd.Escape = scoreEscape
+		sd.Conducts = len(g.IntactConducts()) * scorePerConduct
+	}
+	if g.LiberatedShaedra {
+		sd.Shaedra = scoreShaedra
+	}
+	if g.LiberatedArtifact {
+		sd.Artifact = scoreArtifact
+	}
+	for depth := 1; depth <= maxDepth && depth <= MaxDepth; depth++ {
+		sd.Stealth += (100 - g.Stats.DUSpottedPerc[depth]) * scorePerStealth
+	}
+	for ach := range g.Stats.Achievements {
+		if ach != NoAchievement {
+			sd.Achievements += scorePerAchievement
+		}
+	}
+	return sd
+}
+
+// Score returns the score of the game.
+func (g *game) Score() int {
+	return g.ScoreDetail().Total()
+}
+
+// maxHighScores is the number of games kept in the high-score table.
+const maxHighScores = 20
+
+// highScore is an entry of the high-score table.
+type highScore struct {
+	Score    int
+	Date     string
+	Version  string
+	Seed     int64
+	Daily    string
+	Escaped  bool
+	Depth    int
+	Turns    int
+	Shaedra  bool
+	Artifact bool
+	KilledBy string
+}
+
+func (hs highScore) String() string {
+	outcome := "escaped"
+	if !hs.Escaped {
+		outcome = "died"
+		if hs.KilledBy != "" {
+			outcome = "killed by " + hs.KilledBy
+		}
+	}
+	s := fmt.Sprintf("%6d %s depth %2d, %5d turns, %s", hs.Score, hs.Date, hs.Depth, hs.Turns, outcome)
+	if hs.Shaedra {
+		s += ", Shaedra"
+	}
+	if hs.Artifact {
+		s += ", Artifact"
+	}
+	if hs.Daily != "" {
+		s += " (daily)"
+	}
+	return s
+}
+
+// highScores is the local high-score table, with the best games first.
+type highScores struct {
+	Entries []highScore
+}
+
+// Add adds an entry to the table, if it ranks among the best ones. It
+// returns the entry's rank, starting from 1, or 0 if it was not added.
+func (hs *highScores) Add(e highScore) int {
+	i := sort.Search(len(hs.Entries), func(i int) bool { return hs.Entries[i].Score < e.Score })
+	if i >= maxHighScores {
+		return 0
+	}
+	hs.Entries = append(hs.Entries, highScore{})
+	copy(hs.Entries[i+1:], hs.Entries[i:])
+	hs.Entries[i] = e
+	if len(hs.Entries) > maxHighScores {
+		hs.Entries = hs.Entries[:maxHighScores]
+	}
+	return i + 1
+}
+
+// HighScore returns the high-score entry of the finished game, given the
+// date it ended.
+func (g *game) HighScore(date string) highScore {
+	return highScore{
+		Score:    g.Score(),
+		Date:     date,
+		Version:  Version,
+		Seed:     g.Seed,
+		Daily:    g.Daily,
+		Escaped:  g.Player.HP > 0 && g.Depth == -1,
+		Depth:    max(g.Depth, g.ExploredLevels),
+		Turns:    g.Turn,
+		Shaedra:  g.LiberatedShaedra,
+		Artifact: g.LiberatedArtifact,
+		KilledBy: g.Stats.KilledBy,
+	}
+}
+
+// RecordHighScore adds the finished game to the high-score table, and returns
+// its rank, or 0 if it did not rank. Games in wizard mode are not recorded.
+func (g *game) RecordHighScore(t time.Time) (int, error) {
+	if g.Wizard {
+		return 0, nil
+	}
+	hs, err := LoadHighScores()
+	if err != nil {
+		return 0, err
+	}
+	rank := hs.Add(g.HighScore(t.Format("2006-01-02")))
+	if rank == 0 {
+		return 0, nil
+	}
+	return rank, SaveHighScores(hs)
+}
+
+func (md *model) recordHighScore() {
+	g := md.g
+	rank, err := g.RecordHighScore(md.now())
+	if err != nil {
+		g.PrintfStyled("Error recording high score: %v", logError, err)
+		return
+	}
+	if rank > 0 {
+		g.PrintfStyled("Score: %d (rank %d in the high-score table).", logSpecial, g.Score(), rank)
+	} else {
+		g.Printf("Score: %d.", g.Score())
+	}
+}
+
+func (md *model) openHighScores() {
+	hs, err := LoadHighScores()
+	if err != nil {
+		md.g.PrintfStyled("Error loading high scores: %v", logError, err)
+		return
+	}
+	md.pagerMode = modeHighScores
+	md.mode = modePager
+	md.pager.SetBox(&ui.Box{Title: ui.Text(" High Scores ").WithStyle(gruid.Style{}.WithFg(ColorYellow))})
+	lines := []ui.StyledText{}
+	if len(hs.Entries) == 0 {
+		lines = append(lines, ui.Text(" No finished game yet."))
+	}
+	for i, e := range hs.Entries {
+		lines = append(lines, ui.Textf(" %2d.%s", i+1, e))
+	}
+	md.pager.SetLines(lines)
+	md.pager.SetCursor(gruid.Point{0, 0})
+}
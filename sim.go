@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// sim is a headless game: it runs the game logic without user interface
+// model nor animations, and is driven by the same actions as the normal mode.
+// It is meant for tests and bots.
+type sim struct {
+	g *game
+}
+
+// newSim starts a new headless game with a given seed (0 means time based).
+func newSim(seed int64) *sim {
+	g := &game{Seed: seed}
+	g.InitLevel()
+	g.ComputeMapInfo()
+	return &sim{g: g}
+}
+
+// Over reports whether the game is finished, either by death or by escaping.
+func (s *sim) Over() bool {
+	return s.g.Player.HP <= 0 || s.g.Depth == -1
+}
+
+// Won reports whether the player escaped.
+func (s *sim) Won() bool {
+	return s.g.Player.HP > 0 && s.g.Depth == -1
+}
+
+var errSimOver = errors.New("game is over")
+
+// Do performs a player action and then runs the game until the player's next
+// decision, including automatic actions such as resting or auto-exploration.
+// Actions that only concern the user interface are not available. Interaction
+// with magaras is done with EquipMagara instead.
+func (s *sim) Do(a action) error {
+	if s.Over() {
+		return errSimOver
+	}
+	g := s.g
+	var again bool
+	var err error
+	switch a {
+	case ActionW, ActionS, ActionN, ActionE:
+		again, err = g.PlayerBump(g.Player.P.Add(keyToDir(a)))
+	case ActionRunW, ActionRunS, ActionRunN, ActionRunE:
+		again, err = g.GoToDir(keyToDir(a))
+	case ActionWaitTurn:
+		g.WaitTurn()
+	case ActionGoToStairs:
+		again = true
+		_, err = g.GoToStairs()
+	case ActionInteract:
+		switch terrain(g.Dungeon.Cell(g.Player.P)) {
+		case MagaraCell:
+			err = errors.New("Use EquipMagara to choose a magara slot.")
+		case ScrollCell:
+			again = true
+			if g.Objects.Scrolls[g.Player.P] == ScrollLore {
+				g.LearnLore()
+			}
+		default:
+			again, err = g.Interact()
+		}
+	case ActionExplore:
+		again, err = g.Autoexplore()
+	default:
+		return fmt.Errorf("action not available in headless mode: %v", a)
+	}
+	return s.endAction(again, err)
+}
+
+// Evoke evokes the magara in a given slot.
+func (s *sim) Evoke(i int) error {
+	if s.Over() {
+		return errSimOver
+	}
+	if i < 0 || i >= len(s.g.Player.Magaras) {
+		return fmt.Errorf("invalid magara slot: %d", i)
+	}
+	return s.endAction(false, s.g.UseMagara(i))
+}
+
+// EquipMagara exchanges the magara in a given slot with the one at the
+// player's position.
+func (s *sim) EquipMagara(i int) error {
+	if s.Over() {
+		return errSimOver
+	}
+	g := s.g
+	if terrain(g.Dungeon.Cell(g.Player.P)) != MagaraCell {
+		return errors.New("There is no magara here.")
+	}
+	if i < 0 || i >= len(g.Player.Magaras) {
+		return fmt.Errorf("invalid magara slot: %d", i)
+	}
+	return s.endAction(false, g.EquipMagara(i))
+}
+
+func (s *sim) endAction(again bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !again {
+		s.endTurn()
+	} else if s.g.Player.HP <= 0 {
+		// falling into the abyss
+		s.g.Die()
+	}
+	return nil
+}
+
+// endTurn finalizes the player's turn and runs other events until the next
+// player's turn, like the model's EndTurn.
+func (s *sim) endTurn() {
+	g := s.g
+	for {
+		g.EndTurn()
+		auto := g.AutoPlayer()
+		g.TurnStats()
+		g.ComputeMapInfo()
+		if g.Player.HP <= 0 {
+			g.Die()
+			return
+		}
+		g.LogNextTick = g.LogIndex
+		if !auto || g.Depth == -1 {
+			return
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var simActions = []action{
+	ActionW, ActionS, ActionN, ActionE,
+	ActionRunW, ActionRunS, ActionRunN, ActionRunE,
+	ActionWaitTurn, ActionGoToStairs, ActionInteract, ActionExplore,
+}
+
+// playSim plays a headless game with random actions, and returns the number
+// of actions.
+func playSim(t *testing.T, s *sim, r *rand.Rand, max int) int {
+	for i := 0; i < max; i++ {
+		if s.Over() {
+			return i
+		}
+		var err error
+		switch n := r.Intn(20); {
+		case n == 0:
+			err = s.Evoke(r.Intn(len(s.g.Player.Magaras)))
+		case terrain(s.g.Dungeon.Cell(s.g.Player.P)) == MagaraCell:
+			err = s.EquipMagara(r.Intn(len(s.g.Player.Magaras)))
+		case terrain(s.g.Dungeon.Cell(s.g.Player.P)) == StairCell:
+			err = s.Do(ActionInteract)
+		default:
+			err = s.Do(simActions[r.Intn(len(simActions))])
+		}
+		if err == errSimOver {
+			t.Errorf("action after game over")
+		}
+	}
+	return max
+}
+
+func TestSim(t *testing.T) {
+	DisableAnimations = false
+	defer func() { DisableAnimations = true }()
+	for i := 0; i < 20; i++ {
+		s := newSim(int64(i + 1))
+		playSim(t, s, rand.New(rand.NewSource(int64(i))), 2000)
+		if s.g.md != nil {
+			t.Errorf("headless game has a model")
+		}
+		if s.Over() && s.Do(ActionWaitTurn) != errSimOver {
+			t.Errorf("game not over")
+		}
+	}
+}
+
+func TestSimDeterminism(t *testing.T) {
+	var turns [2]int
+	var depths [2]int
+	for i := range turns {
+		s := newSim(7)
+		playSim(t, s, rand.New(rand.NewSource(7)), 500)
+		turns[i] = s.g.Turn
+		depths[i] = s.g.Depth
+	}
+	if turns[0] != turns[1] || depths[0] != depths[1] {
+		t.Errorf("different outcomes: turns %v, depths %v", turns, depths)
+	}
+}
+
+// levelDump returns the terrain and monsters of the current level.
+func levelDump(g *game) string {
+	var b strings.Builder
+	it := g.Dungeon.Grid.Iterator()
+	for it.Next() {
+		fmt.Fprintf(&b, "%d,", terrain(cell(it.Cell())))
+	}
+	for _, mons := range g.Monsters {
+		fmt.Fprintf(&b, "\n%v %v", mons.Kind, mons.P)
+	}
+	return b.String()
+}
+
+func TestDailyLevels(t *testing.T) {
+	date := "2026-10-18"
+	var levels [2][]string
+	for i := range levels {
+		g := &game{Seed: dailySeed(date), Daily: date}
+		g.InitLevel()
+		g.ComputeMapInfo()
+		s := &sim{g: g}
+		// different action sequences on the first level
+		r := rand.New(rand.NewSource(int64(i)))
+		for j := 0; j < 100*i; j++ {
+			if s.Over() {
+				g.Player.HP = g.Player.HPMax()
+			}
+			if r.Intn(10) == 0 {
+				s.Evoke(r.Intn(len(g.Player.Magaras)))
+				continue
+			}
+			s.Do([]action{ActionW, ActionS, ActionN, ActionE, ActionWaitTurn, ActionExplore}[r.Intn(6)])
+		}
+		for g.Depth < 4 {
+			g.Descend(DescendNormal)
+			levels[i] = append(levels[i], levelDump(g))
+		}
+	}
+	for j := range levels[0] {
+		if levels[0][j] != levels[1][j] {
+			t.Errorf("depth %d differs", j+2)
+		}
+	}
+}
+
+func TestSimStory(t *testing.T) {
+	s := newSim(3)
+	g := s.g
+	for g.Depth < WinDepth {
+		g.Descend(DescendNormal)
+	}
+	g.PushEventFirst(&playerEvent{Action: StorySequence}, g.Turn)
+	s.endTurn()
+	if terrain(g.Dungeon.Cell(g.Places.Shaedra)) != GroundCell {
+		t.Errorf("Shaedra not freed")
+	}
+	if g.Objects.Stairs[g.Places.Monolith] != WinStair {
+		t.Errorf("no escape portal")
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	s := newSim(5)
+	playSim(t, s, rand.New(rand.NewSource(5)), 1000)
+	g := s.g
+	g.Stats.KilledMons[MonsGuard] = 2
+	g.Stats.UsedMagaras[BlinkMagara] = 1
+	data, err := g.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d struct {
+		dumpJSON
+		Stats map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != Version || d.Seed != 5 || d.Outcome.Turns != g.Turn {
+		t.Errorf("bad dump header: %+v", d)
+	}
+	if len(d.Timeline) != len(g.Stats.Timeline) || d.Timeline[0].Depth != 1 || d.Timeline[0].Text == "" || d.Timeline[0].Kind == "" {
+		t.Errorf("bad timeline: %+v", d.Timeline)
+	}
+	var dspotted []int
+	if err := json.Unmarshal(d.Stats["DSpotted"], &dspotted); err != nil || len(dspotted) != MaxDepth+1 {
+		t.Errorf("bad per-depth statistics: %s", d.Stats["DSpotted"])
+	}
+	var killed map[string]int
+	if err := json.Unmarshal(d.Stats["KilledMons"], &killed); err != nil || killed["guard"] != 2 {
+		t.Errorf("bad killed monsters: %s", d.Stats["KilledMons"])
+	}
+	var used map[string]int
+	if err := json.Unmarshal(d.Stats["UsedMagaras"], &used); err != nil || used["magara of blinking"] != 1 {
+		t.Errorf("bad used magaras: %s", d.Stats["UsedMagaras"])
+	}
+	if _, ok := d.Stats["Story"]; ok {
+		t.Errorf("timeline in statistics")
+	}
+	for _, name := range []string{"Achievements", "Statuses", "Turns", "KilledBy"} {
+		if _, ok := d.Stats[name]; !ok {
+			t.Errorf("no %s statistics", name)
+		}
+	}
+}
+
+func TestHeatmaps(t *testing.T) {
+	s := newSim(7)
+	playSim(t, s, rand.New(rand.NewSource(7)), 1000)
+	g := s.g
+	if len(g.Stats.DVisits[1]) == 0 {
+		t.Fatal("no visits recorded on depth 1")
+	}
+	if g.Stats.DVisits[g.Depth][g.Player.P] == 0 {
+		t.Errorf("current position %v not visited", g.Player.P)
+	}
+	hm := heatmap{{1, 2}: 1, {3, 2}: 4}
+	lines := strings.Split(strings.TrimRight(hm.Render(), "\n"), "\n")
+	if len(lines) != DungeonHeight+2 {
+		t.Fatalf("bad heatmap height: %d", len(lines))
+	}
+	if row := []rune(lines[3]); len(row) != DungeonWidth+2 || row[2] != '░' || row[4] != '█' || row[1] != ' ' {
+		t.Errorf("bad heatmap row: %q", lines[3])
+	}
+	dump := g.DumpHeatmaps()
+	if !strings.Contains(dump, "Depth 1, visits (") {
+		t.Errorf("no depth 1 visits in heatmaps dump:\n%s", dump)
+	}
+	data, err := g.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d struct {
+		Stats struct {
+			DVisits []map[string]int
+		}
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Stats.DVisits) != MaxDepth+1 || len(d.Stats.DVisits[1]) != len(g.Stats.DVisits[1]) {
+		t.Errorf("bad visits in JSON dump: %d levels", len(d.Stats.DVisits))
+	}
+}
+
+func TestBlackout(t *testing.T) {
+	s := newSim(5)
+	g := s.g
+	g.Params.Event[2] = BlackoutLevel
+	g.Descend(DescendNormal)
+	if g.Depth != 2 {
+		t.Fatalf("bad depth: %d", g.Depth)
+	}
+	cats := 0
+	for _, m := range g.Monsters {
+		if m.Kind == MonsHazeCat {
+			cats++
+		}
+	}
+	if cats == 0 {
+		t.Errorf("no haze cats")
+	}
+	announced := false
+	for _, e := range g.Stats.Timeline {
+		if e.Depth == 2 && e.Text == "Special event: blackout" {
+			announced = true
+		}
+	}
+	if !announced {
+		t.Errorf("no story entry")
+	}
+	lit := func() int {
+		n := 0
+		for _, on := range g.Objects.Lights {
+			if on {
+				n++
+			}
+		}
+		return n
+	}
+	n := lit()
+	if n == 0 {
+		t.Fatalf("no lights")
+	}
+	for i := 0; i < n; i++ {
+		g.LightFailure()
+	}
+	if lit() != 0 {
+		t.Errorf("lights still on: %d", lit())
+	}
+	for p, on := range g.Objects.Lights {
+		if !on && terrain(g.Dungeon.Cell(p)) != ExtinguishedLightCell {
+			t.Errorf("light at %v not extinguished", p)
+		}
+	}
+	checked := false
+	for _, m := range g.Monsters {
+		if m.Kind != MonsHazeCat || !m.Exists() {
+			continue
+		}
+		m.ComputeLOS(g)
+		behind := invalidPos
+		for p := range m.LOS {
+			if m.LOS[p] && !inViewCone(m.Dir, m.P, p) && !g.Illuminated(p) {
+				behind = p
+				break
+			}
+		}
+		if behind == invalidPos {
+			continue
+		}
+		if !m.SeesAround(g, behind) {
+			t.Errorf("haze cat does not see around at %v", behind)
+		}
+		g.Params.Event[2] = NormalLevel
+		if m.SeesAround(g, behind) {
+			t.Errorf("haze cat sees around outside blackout")
+		}
+		g.Params.Event[2] = BlackoutLevel
+		checked = true
+		break
+	}
+	if !checked {
+		t.Errorf("no haze cat with a position behind it")
+	}
+}
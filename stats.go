@@ -1,11 +1,13 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/anaseto/gruid"
 )
 
 type stats struct {
-	Story             []string
+	Timeline          []timelineEntry
 	Killed            int
 	KilledMons        map[monsterKind]int
 	Moves             int
@@ -55,6 +57,11 @@ type stats struct {
 	TimesPushed       int
 	TimesBlinked      int
 	TimesBlocked      int
+	KilledBy          string                // cause of death (usually a monster kind)
+	DVisits           [MaxDepth + 1]heatmap // player visits by cell
+	DNoise            [MaxDepth + 1]heatmap // noise made at the player's position
+	DSpottedAt        [MaxDepth + 1]heatmap // player positions when spotted
+	BrokenConducts    map[conduct]conductBreak
 }
 
 func (g *game) TurnStats() {
@@ -86,16 +93,7 @@ func (g *game) LevelStats() {
 		}
 	}
 	g.Stats.DExplPerc[g.Depth] = exp * 100 / free
-	if g.Stats.DExplPerc[g.Depth] > 93 {
-		AchNoviceExplorer.Get(g)
-	}
-	if g.Depth >= 5 && g.Stats.DExplPerc[g.Depth] > 93 && g.Stats.DExplPerc[g.Depth-1] > 93 && g.Stats.DExplPerc[g.Depth-2] > 93 {
-		AchInitiateExplorer.Get(g)
-	}
-	if g.Depth >= 8 && g.Stats.DExplPerc[g.Depth] > 93 && g.Stats.DExplPerc[g.Depth-1] > 93 && g.Stats.DExplPerc[g.Depth-2] > 93 &&
-		g.Stats.DExplPerc[g.Depth-3] > 93 && g.Stats.DExplPerc[g.Depth-4] > 93 {
-		AchMasterExplorer.Get(g)
-	}
+	g.CheckAchievements(CounterExploredLevels)
 	//g.Stats.DBurns[g.Depth] = g.Stats.CurBurns // XXX to avoid little dump info leak
 	nmons := len(g.Monsters)
 	kmons := 0
@@ -161,13 +159,17 @@ const (
 	AchAntimagicNovice     achievement = "Antimagic Novice"
 	AchAntimagicInitiate   achievement = "Antimagic Initiate"
 	AchAntimagicMaster     achievement = "Antimagic Master"
+	AchPacifist            achievement = "Pacifist Escape"
+	AchMagaraless          achievement = "Magaraless Escape"
+	AchRestless            achievement = "Restless Escape"
+	AchUnseen              achievement = "Unseen Escape"
 )
 
 func (ach achievement) Get(g *game) {
 	if g.Stats.Achievements[ach] == 0 {
 		g.Stats.Achievements[ach] = g.Turn
 		g.PrintfStyled("Achievement: %s.", logSpecial, ach)
-		g.StoryPrintf("Achievement: %s", ach)
+		g.StoryEntry(timelineEntry{Kind: TimelineAchievement, Name: string(ach), Text: fmt.Sprintf("Achievement: %s", ach)})
 	}
 }
 
@@ -189,28 +191,14 @@ func (g *game) Reach(p gruid.Point) {
 	switch terrain(c) {
 	case TreeCell:
 		g.Stats.ClimbedTree++
-		if g.Stats.ClimbedTree == 12 {
-			AchTree.Get(g)
-		}
 	case TableCell:
 		g.Stats.TableHides++
-		if g.Stats.TableHides == 12 {
-			AchTable.Get(g)
-		}
 	case HoledWallCell:
 		g.Stats.HoledWallsCrawled++
-		if g.Stats.HoledWallsCrawled == 12 {
-			AchHole.Get(g)
-		}
 	case DoorCell:
 		g.Stats.DoorsOpened++
-		if g.Stats.DoorsOpened == 100 {
-			AchDoors.Get(g)
-		}
 	case BarrelCell:
 		g.Stats.BarrelHides++
-		if g.Stats.BarrelHides == 20 {
-			AchBarrels.Get(g)
-		}
 	}
+	g.CheckAchievements(CounterClimbedTree, CounterTableHides, CounterHoledWallsCrawled, CounterDoorsOpened, CounterBarrelHides)
 }
@@ -363,8 +363,11 @@ func (g *game) computePathHighlight(p gruid.Point) {
 }
 
 func (md *model) target() error {
-	g := md.g
-	p := md.targ.ex.p
+	return md.g.SetAutoTarget(md.targ.ex.p)
+}
+
+// SetAutoTarget sets the destination of the player's travel.
+func (g *game) SetAutoTarget(p gruid.Point) error {
 	if !explored(g.Dungeon.Cell(p)) {
 		return errors.New("You do not know this place.")
 	}
@@ -91,74 +91,5 @@ func map16ColorToLight(c gruid.Color) gruid.Color {
 	}
 }
 
-// xterm solarized colors: http://ethanschoonover.com/solarized
-const (
-	Color256Base03  gruid.Color = 234
-	Color256Base02  gruid.Color = 235
-	Color256Base01  gruid.Color = 240
-	Color256Base00  gruid.Color = 241 // for dark on light background
-	Color256Base0   gruid.Color = 244
-	Color256Base1   gruid.Color = 245
-	Color256Base2   gruid.Color = 254
-	Color256Base3   gruid.Color = 230
-	Color256Yellow  gruid.Color = 136
-	Color256Orange  gruid.Color = 166
-	Color256Red     gruid.Color = 160
-	Color256Magenta gruid.Color = 125
-	Color256Violet  gruid.Color = 61
-	Color256Blue    gruid.Color = 33
-	Color256Cyan    gruid.Color = 37
-	Color256Green   gruid.Color = 64
-)
-
-func map16ColorTo256(c gruid.Color, fg bool) gruid.Color {
-	switch c {
-	case ColorBackground:
-		if fg {
-			if GameConfig.DarkLOS {
-				return Color256Base0
-			}
-			return Color256Base00
-		}
-		if GameConfig.DarkLOS {
-			return Color256Base03
-		}
-		return Color256Base3
-	case ColorBackgroundSecondary:
-		if GameConfig.DarkLOS {
-			return Color256Base02
-		}
-		return Color256Base2
-	case ColorForegroundEmph:
-		if GameConfig.DarkLOS {
-			return Color256Base1
-		}
-		return Color256Base01
-	case ColorForegroundSecondary:
-		if GameConfig.DarkLOS {
-			return Color256Base01
-		}
-		return Color256Base1
-	case ColorYellow:
-		return Color256Yellow
-	case ColorOrange:
-		return Color256Orange
-	case ColorRed:
-		return Color256Red
-	case ColorMagenta:
-		return Color256Magenta
-	case ColorViolet:
-		return Color256Violet
-	case ColorBlue:
-		return Color256Blue
-	case ColorCyan:
-		return Color256Cyan
-	case ColorGreen:
-		return Color256Green
-	default:
-		return c
-	}
-}
-
 func clearCache() {
 }
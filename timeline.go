@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// timelineKind is the kind of a timeline entry. Kinds are recorded by name,
+// so that they can be used as filters in JSON dumps.
+type timelineKind string
+
+// Kinds of timeline entries.
+const (
+	TimelineOther       timelineKind = "other"
+	TimelineDescent     timelineKind = "descent"
+	TimelineMagaraUse   timelineKind = "magara-use"
+	TimelineItemFound   timelineKind = "item-found"
+	TimelineAchievement timelineKind = "achievement"
+	TimelineKill        timelineKind = "monster-killed"
+	TimelineRescue      timelineKind = "rescue"
+	TimelineNearDeath   timelineKind = "near-death"
+)
+
+// timelineKinds lists the kinds of timeline entries, in the order used by
+// timeline filters.
+var timelineKinds = []timelineKind{
+	TimelineDescent,
+	TimelineMagaraUse,
+	TimelineItemFound,
+	TimelineAchievement,
+	TimelineKill,
+	TimelineRescue,
+	TimelineNearDeath,
+	TimelineOther,
+}
+
+// timelineEntry is an entry of the game's timeline.
+type timelineEntry struct {
+	Depth int
+	Turn  int
+	Kind  timelineKind
+	Text  string
+	Name  string `json:",omitempty"` // monster, magara, item, achievement, rescued one or descent way
+	HP    int    `json:",omitempty"` // remaining HP (near death)
+}
+
+// String returns the text rendering of the entry, as found in dumps.
+func (e timelineEntry) String() string {
+	return fmt.Sprintf("Depth %2d|Turn %5d| %s", e.Depth, e.Turn, e.Text)
+}
+
+// StoryEntry adds an entry to the timeline at the current depth and turn.
+func (g *game) StoryEntry(e timelineEntry) {
+	e.Depth = g.Depth
+	e.Turn = g.Turn
+	if e.Kind == "" {
+		e.Kind = TimelineOther
+	}
+	g.Stats.Timeline = append(g.Stats.Timeline, e)
+}
+
+// filterTimeline returns the entries of a given kind, or all the entries if
+// kind is empty.
+func filterTimeline(entries []timelineEntry, kind timelineKind) []timelineEntry {
+	if kind == "" {
+		return entries
+	}
+	fentries := []timelineEntry{}
+	for _, e := range entries {
+		if e.Kind == kind {
+			fentries = append(fentries, e)
+		}
+	}
+	return fentries
+}
+
+// parseStoryEntry parses a timeline entry in text form, as written by
+// versions that recorded the timeline as strings. The kind is guessed from
+// the text, and kind-specific data is lost.
+func parseStoryEntry(s string) timelineEntry {
+	fields := strings.SplitN(s, "|", 3)
+	if len(fields) != 3 {
+		return timelineEntry{Kind: TimelineOther, Text: s}
+	}
+	depth, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(fields[0], "Depth")))
+	turn, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(fields[1], "Turn")))
+	e := timelineEntry{Depth: depth, Turn: turn, Kind: TimelineOther, Text: strings.TrimSpace(fields[2])}
+	switch {
+	case strings.HasPrefix(e.Text, "Descended"), e.Text == "Fell into the abyss":
+		e.Kind = TimelineDescent
+	case strings.HasPrefix(e.Text, "Evoked "):
+		e.Kind = TimelineMagaraUse
+	case strings.HasPrefix(e.Text, "Spotted "):
+		e.Kind = TimelineItemFound
+	case strings.HasPrefix(e.Text, "Achievement: "):
+		e.Kind = TimelineAchievement
+		e.Name = strings.TrimPrefix(e.Text, "Achievement: ")
+	case strings.HasPrefix(e.Text, "Death of "):
+		e.Kind = TimelineKill
+	case strings.HasPrefix(e.Text, "Critical hit by "):
+		e.Kind = TimelineNearDeath
+	}
+	return e
+}
@@ -16,6 +16,9 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// RandInt returns a random integer in [0, n) using the global source. It is
+// only used for things that do not affect the game state, like animations:
+// game logic uses the game's seeded source instead (see game.randInt).
 func RandInt(n int) int {
 	if n <= 0 {
 		return 0
@@ -24,6 +27,29 @@ func RandInt(n int) int {
 	return x
 }
 
+// rng is a splitmix64 pseudo-random source. Its state is a single exported
+// integer, so that it can be saved with the game: a loaded game then goes on
+// with the same random sequence.
+type rng struct {
+	State uint64
+}
+
+func (r *rng) Seed(seed int64) {
+	r.State = uint64(seed)
+}
+
+func (r *rng) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (r *rng) Int63() int64 {
+	return int64(r.Uint64() >> 1)
+}
+
 func max(x, y int) int {
 	if x > y {
 		return x
@@ -31,6 +57,13 @@ func max(x, y int) int {
 	return y
 }
 
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
 func Indefinite(s string, upper bool) (text string) {
 	if len(s) > 0 {
 		switch s[0] {
@@ -0,0 +1,305 @@
+package main
+
+// This file implements vaults: large prefabs of several rooms, with their own
+// monster bands, lights, barrels and a guaranteed reward. A vault is placed
+// like a room, but it is connected to the rest of the level only after the
+// other rooms, so that the level does not depend on it, and its entries then
+// follow the vault's entry rule.
+//
+// Vault layouts use the room runes (see roomRunes), with the following
+// meaning for places: P places are the band positions, _ places are barrels,
+// and the ! place is the reward.
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// vaultEntry is the rule for entering a vault.
+type vaultEntry int
+
+const (
+	// VaultHoledWalls vaults are entered through holed walls only.
+	VaultHoledWalls vaultEntry = iota
+	// VaultWindows vaults are entered through a single holed wall, and
+	// their other entries are windows.
+	VaultWindows
+)
+
+// vaultReward is the kind of reward guaranteed in a vault.
+type vaultReward int
+
+const (
+	VaultRareMagara vaultReward = iota
+	VaultAmulet
+	VaultPotion
+)
+
+func (vr vaultReward) String() (text string) {
+	switch vr {
+	case VaultRareMagara:
+		text = "rare magara"
+	case VaultAmulet:
+		text = "amulet"
+	case VaultPotion:
+		text = "potion"
+	}
+	return text
+}
+
+// vaultDef defines a vault. The room template's depth range and weight are
+// used to pick vaults for a level.
+type vaultDef struct {
+	Room    roomTemplate
+	Entry   vaultEntry
+	Bands   []monsterBand
+	Barrels int
+	Reward  vaultReward
+}
+
+// levelVault contains information about the vault of the current level.
+type levelVault struct {
+	Def    vaultDef
+	Reward gruid.Point
+}
+
+const (
+	VaultTreasury = `
+?###############?
+#_.l.#.....#.l._#
+#..P.|..!..|.P..#
+#_...#.....#..._#
+##|###########|##
++....P.....P....+
+#_l...........l_#
+?#######+#######?`
+	VaultStudy = `
+?###+#########?
+#_.π.π.#l....π#
+#.P....|...!..#
+#l.π.π.#π....l#
+##|#########|##
++..P........_.+
+?#############?`
+	VaultCrypt = `
+?#####+#####?
+#l.._#.#_..l#
+#.P..|.|..P.#
+#_..##!##.._#
+##|#######|##
+#..P.....P..#
++.._..l..._.+
+?###########?`
+	VaultMenagerie = `
+?##+#####+##?
+#_..#l..#.._#
+#.P.|.!.|.P.#
+#_..#..l#.._#
+?##+#####+##?`
+)
+
+func vaultRoom(name, layout string, min, max, weight int) roomTemplate {
+	return roomTemplate{Name: name, Layout: layout, Kind: roomVault, MinDepth: min, MaxDepth: max, Weight: weight}
+}
+
+// vaults contains the vaults used by the level generator.
+var vaults = []vaultDef{
+	{Room: vaultRoom("VaultTreasury", VaultTreasury, 3, 10, 2), Entry: VaultHoledWalls,
+		Bands: []monsterBand{LoneGuard, LoneGuard, LoneHighGuard}, Barrels: 3, Reward: VaultAmulet},
+	{Room: vaultRoom("VaultStudy", VaultStudy, 6, 10, 1), Entry: VaultWindows,
+		Bands: []monsterBand{LoneOricCelmist, LoneHarmonicCelmist}, Barrels: 2, Reward: VaultRareMagara},
+	{Room: vaultRoom("VaultCrypt", VaultCrypt, 5, 10, 1), Entry: VaultWindows,
+		Bands: []monsterBand{LoneVampire, LoneMirrorSpecter, LoneVampire}, Barrels: 2, Reward: VaultRareMagara},
+	{Room: vaultRoom("VaultMenagerie", VaultMenagerie, 3, 7, 1), Entry: VaultHoledWalls,
+		Bands: []monsterBand{LoneDog, LoneYack}, Barrels: 2, Reward: VaultPotion},
+}
+
+// vaultsFor returns the vaults that can be used at the given depth.
+func vaultsFor(depth int) []vaultDef {
+	vds := []vaultDef{}
+	for _, vd := range vaults {
+		if vd.Room.Allowed(depth) {
+			vds = append(vds, vd)
+		}
+	}
+	return vds
+}
+
+// pickVault returns a random vault, according to the weights of the vault
+// room templates.
+func (dg *dgen) pickVault(vds []vaultDef) vaultDef {
+	tpls := []roomTemplate{}
+	for _, vd := range vds {
+		tpls = append(tpls, vd.Room)
+	}
+	tpl := dg.pickRoomTemplate(tpls)
+	for _, vd := range vds {
+		if vd.Room.Name == tpl.Name {
+			return vd
+		}
+	}
+	return vds[len(vds)-1]
+}
+
+// GenVault places a vault for the current depth, if any, and reports whether
+// it did. The vault room is kept apart from the other rooms until
+// ConnectVault.
+func (dg *dgen) GenVault(g *game) bool {
+	if !g.Params.Vaults[g.Depth] {
+		return false
+	}
+	vds := vaultsFor(g.Depth)
+	if len(vds) == 0 {
+		return false
+	}
+	vd := dg.pickVault(vds)
+	if _, ok := dg.GenRooms([]roomTemplate{vd.Room}, 1, PlacementRandom); !ok {
+		return false
+	}
+	r := dg.rooms[len(dg.rooms)-1]
+	dg.rooms = dg.rooms[:len(dg.rooms)-1]
+	dg.vaultRoom = r
+	dg.vaultDef = vd
+	dg.vaultCells = map[gruid.Point]bool{}
+	r.vault.Iter(func(p gruid.Point, c rune) {
+		if c != '?' {
+			dg.vaultCells[r.p.Add(p)] = true
+		}
+	})
+	return true
+}
+
+// ConnectVault connects the vault to the other rooms, once these are
+// connected together. Vaults with windows get an extra tunnel, so that there
+// is something to watch through.
+func (dg *dgen) ConnectVault() {
+	if dg.vaultRoom == nil {
+		return
+	}
+	dg.rooms = append(dg.rooms, dg.vaultRoom)
+	i := len(dg.rooms) - 1
+	dg.ConnectRoomsShortestPath(dg.nearestConnectedRoom(i), i)
+	if dg.vaultDef.Entry == VaultWindows {
+		dg.ConnectRoomsShortestPath(i, dg.nearRoom(i))
+	}
+}
+
+// SealVault applies the vault's entry rule to the used vault entries, after
+// doors have been put, and removes the vault from the rooms, so that stairs,
+// items and the player are placed elsewhere.
+func (dg *dgen) SealVault(g *game) {
+	r := dg.vaultRoom
+	if r == nil {
+		return
+	}
+	entries := []gruid.Point{}
+	for _, e := range r.entries {
+		if e.used && !e.virtual {
+			entries = append(entries, e.p)
+		}
+	}
+	dg.rooms = dg.rooms[:len(dg.rooms)-1]
+	if len(entries) == 0 {
+		// should not happen: the vault could not be connected
+		dg.vaultRoom = nil
+		return
+	}
+	hole := dg.rand.Intn(len(entries))
+	for i, p := range entries {
+		if dg.vaultDef.Entry == VaultWindows && i != hole {
+			dg.d.SetCell(p, WindowCell)
+		} else {
+			dg.d.SetCell(p, HoledWallCell)
+		}
+	}
+}
+
+// GenVaultContents puts the vault's barrels and reward.
+func (dg *dgen) GenVaultContents(g *game) {
+	r := dg.vaultRoom
+	if r == nil {
+		return
+	}
+	for i := 0; i < dg.vaultDef.Barrels; i++ {
+		p := r.RandomPlace(dg, PlaceStatic)
+		if p == invalidPos {
+			break
+		}
+		dg.d.SetCell(p, BarrelCell)
+		g.Objects.Barrels[p] = true
+	}
+	p := r.RandomPlace(dg, PlaceItem)
+	if p == invalidPos {
+		return
+	}
+	g.vault = &levelVault{Def: dg.vaultDef, Reward: p}
+	switch dg.vaultDef.Reward {
+	case VaultRareMagara:
+		if mag, ok := g.RandomRareMagara(); ok {
+			dg.d.SetCell(p, MagaraCell)
+			g.Objects.Magaras[p] = mag
+			g.GeneratedMagaras = append(g.GeneratedMagaras, mag.Kind)
+			return
+		}
+	case VaultAmulet:
+		if len(g.GeneratedAmulets) < len(amulets) {
+			it := g.RandomAmulet()
+			dg.d.SetCell(p, ItemCell)
+			g.Objects.Items[p] = it
+			g.GeneratedAmulets = append(g.GeneratedAmulets, it)
+			return
+		}
+	}
+	// potion reward, or fallback when the reward was already generated
+	dg.d.SetCell(p, PotionCell)
+	g.Objects.Potions[p] = HealthPotion
+}
+
+// rareMagaras are the magaras that may be found as vault rewards.
+var rareMagaras = []magaraKind{EnergyMagara, TransparencyMagara, DisguiseMagara, DelayedNoiseMagara}
+
+// RandomRareMagara returns a rare magara that was not generated yet, if
+// possible.
+func (g *game) RandomRareMagara() (magara, bool) {
+	mags := []magaraKind{}
+loop:
+	for _, mag := range rareMagaras {
+		for _, m := range g.GeneratedMagaras {
+			if m == mag {
+				continue loop
+			}
+		}
+		mags = append(mags, mag)
+	}
+	if len(mags) == 0 {
+		return magara{}, false
+	}
+	mag := mags[g.randInt(len(mags))]
+	return magara{Kind: mag, Charges: mag.DefaultCharges()}, true
+}
+
+// PutVaultBands puts the vault's monster bands. They patrol between the
+// vault's band places, or guard one of them, and are mostly asleep.
+func (dg *dgen) PutVaultBands(g *game) {
+	r := dg.vaultRoom
+	if r == nil {
+		return
+	}
+	targets := []gruid.Point{}
+	for _, pl := range r.places {
+		if pl.kind == PlacePatrol {
+			targets = append(targets, pl.p)
+		}
+	}
+	for _, band := range dg.vaultDef.Bands {
+		p := r.RandomPlace(dg, PlacePatrol)
+		if p == invalidPos || g.MonsterAt(p).Exists() {
+			continue
+		}
+		bdinf := bandInfo{Kind: band, Path: []gruid.Point{p}, Beh: BehGuard}
+		if target := targets[dg.rand.Intn(len(targets))]; target != p && dg.rand.Intn(3) > 0 {
+			bdinf.Path = append(bdinf.Path, target)
+			bdinf.Beh = BehPatrol
+		}
+		dg.placeBand(g, g.GenBand(band), bdinf, dg.rand.Intn(4) == 0)
+	}
+}